@@ -6,21 +6,29 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/jaam8/wb_tech_school_l0/internal/config"
 	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/handlers"
 	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/middlewares"
+	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/schemas"
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
 	"github.com/jaam8/wb_tech_school_l0/internal/ports/adapters/broker"
 	"github.com/jaam8/wb_tech_school_l0/internal/ports/adapters/cache"
 	"github.com/jaam8/wb_tech_school_l0/internal/ports/adapters/storage"
 	"github.com/jaam8/wb_tech_school_l0/internal/service"
+	"github.com/jaam8/wb_tech_school_l0/pkg/bloom"
+	"github.com/jaam8/wb_tech_school_l0/pkg/health"
 	"github.com/jaam8/wb_tech_school_l0/pkg/kafka"
 	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
 	lrucache "github.com/jaam8/wb_tech_school_l0/pkg/lru-cache"
 	"github.com/jaam8/wb_tech_school_l0/pkg/postgres"
+	"github.com/jaam8/wb_tech_school_l0/pkg/redis"
+	goredis "github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
@@ -38,8 +46,11 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	ctx = context.WithValue(ctx, logger.KeyForLogLevel, cfg.LogLevel)
-	ctx, _ = logger.New(ctx)
+	if _, err = logger.Init(cfg.Logger); err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	defer logger.Sync()
+	go reloadLogLevelsOnSIGHUP(ctx)
 
 	cacheCfg := cfg.Cache
 	postgresCfg := cfg.Postgres
@@ -50,49 +61,341 @@ func main() {
 		log.Fatalf("failed to create postgres client: %v", err)
 	}
 
+	migrated := health.NewFlag()
 	err = postgres.Migrate(ctx, postgresCfg, cfg.MigrationsPath)
 	if err != nil {
 		log.Fatalf("failed to migrate postgres: %v", err)
 	}
+	migrated.Set()
 
 	consumer := kafka.NewReader(ctx, cfg.Kafka, appCfg.KafkaTopic, appCfg.KafkaGroupID)
 
-	inMemoryCache := lrucache.New(
-		cacheCfg.Capacity,
-		time.Duration(cacheCfg.TTL)*time.Minute,
+	postgresAdapter := storage.NewPostgresAdapter(pgClient).WithCopy(appCfg.CopyMinBatch)
+	kafkaAdapter := broker.NewKafkaConsumerAdapter(consumer)
+	var orderTTL cache.OrderTTL
+	if cfg.OrderTTL.Enabled {
+		orderTTL = cache.AgeBasedTTL(
+			time.Duration(cfg.OrderTTL.HotMinutes)*time.Minute,
+			time.Duration(cfg.OrderTTL.ColdMinutes)*time.Minute,
+			time.Duration(cfg.OrderTTL.RecentDays)*24*time.Hour,
+		)
+	}
+	var orderCache cache.Tier
+	// inMemoryCache and notFoundCache are nil unless orders are cached in memory,
+	// redisClient and redisCacheAdapter are nil unless they are cached in Redis
+	var inMemoryCache lrucache.Cache[string, *models.Order]
+	var notFoundCache *lrucache.InMemoryCache[string, struct{}]
+	var redisClient *goredis.Client
+	var redisCacheAdapter *cache.RedisCacheAdapter
+	var tieredCacheAdapter *cache.TieredCacheAdapter
+	switch cfg.OrderCache.Backend {
+	case config.CacheBackendMemory:
+		var inMemoryCacheAdapter *cache.InMemoryCacheAdapter
+		inMemoryCacheAdapter, inMemoryCache, notFoundCache, err = newInMemoryOrderCache(ctx, cfg, orderTTL)
+		orderCache = inMemoryCacheAdapter
+	case config.CacheBackendRedis:
+		redisCacheAdapter, redisClient, err = newRedisOrderCache(ctx, cfg, orderTTL)
+		orderCache = redisCacheAdapter
+	case config.CacheBackendTiered:
+		var local *cache.InMemoryCacheAdapter
+		local, inMemoryCache, notFoundCache, err = newInMemoryOrderCache(ctx, cfg, nil)
+		if err != nil {
+			break
+		}
+		redisCacheAdapter, redisClient, err = newRedisOrderCache(ctx, cfg, orderTTL)
+		if err != nil {
+			break
+		}
+		invalidator := cache.NewRedisInvalidator(redisClient, cfg.OrderCache.KeyPrefix+"invalidate")
+		tieredCacheAdapter = cache.NewTieredCacheAdapter(local, redisCacheAdapter, invalidator)
+		orderCache = tieredCacheAdapter
+	default:
+		err = fmt.Errorf("unknown cache backend %q", cfg.OrderCache.Backend)
+	}
+	if err != nil {
+		log.Fatalf("failed to create cache: %v", err)
+	}
+	if redisClient != nil {
+		defer redisClient.Close()
+	}
+	srvc := service.New(orderCache, kafkaAdapter, postgresAdapter)
+	if cfg.NotFound.BloomEnabled {
+		srvc.UseKnownOrdersFilter(bloom.New(cfg.NotFound.BloomCapacity, cfg.NotFound.BloomFPRate))
+	}
+	// the known orders are scanned once the invalidations of the orders stored later are sure to arrive
+	waitForInvalidations := func(context.Context) error { return nil }
+	if cfg.Invalidation.Enabled {
+		err = kafka.CreateTopicWithRetry(cfg.Kafka, cfg.Invalidation.Topic,
+			1, appCfg.KafkaReplicationFactor, appCfg.MaxRetries, kafka.Compacted,
+		)
+		if err != nil {
+			log.Fatalf("failed to create invalidation topic: %v", err)
+		}
+		invalidationProducer := kafka.NewWriter(ctx, cfg.Kafka, cfg.Invalidation.Topic)
+		defer invalidationProducer.Close()
+		invalidationConsumer := kafka.NewBroadcastReader(ctx, cfg.Kafka, cfg.Invalidation.Topic, cfg.Invalidation.GroupPrefix)
+		defer invalidationConsumer.Close()
+		waitForInvalidations = func(ctx context.Context) error {
+			return kafka.WaitForAssignment(ctx, invalidationConsumer, 100*time.Millisecond)
+		}
+		srvc.UseInvalidator(
+			broker.NewKafkaInvalidator(invalidationProducer, invalidationConsumer),
+			cfg.Invalidation.Refresh,
+		)
+	}
+	var partitionMaintainer *service.PartitionMaintainer
+	if cfg.Partitions.Enabled {
+		action := cfg.Partitions.RetentionAction
+		if action != config.RetentionDetach && action != config.RetentionDrop {
+			log.Fatalf("unknown partition retention action %q", action)
+		}
+		partitionMaintainer = service.NewPartitionMaintainer(
+			storage.NewPostgresPartitionAdapter(pgClient),
+			cfg.Partitions.AheadMonths,
+			cfg.Partitions.RetentionMonths,
+			action == config.RetentionDrop,
+			time.Duration(cfg.Partitions.IntervalMinutes)*time.Minute,
+		).WithArchive(cfg.Partitions.ArchiveDir)
+	}
+	auditor := service.NewAuditor(
+		storage.NewPostgresAuditAdapter(pgClient),
+		cfg.Audit.BufferSize,
+		cfg.Audit.BatchSize,
+		time.Duration(cfg.Audit.FlushIntervalMs)*time.Millisecond,
+	).WithRecordTimeout(time.Duration(cfg.Audit.RecordTimeoutMs) * time.Millisecond)
+	handler := handlers.NewHandler(srvc, auditor)
+	adminHandler := handlers.NewAdminHandler(auditor, orderCache, srvc, cfg)
+
+	cacheWarmedUp := health.NewFlag()
+	healthRegistry := health.NewRegistry(
+		time.Duration(cfg.Health.CacheTTLMs)*time.Millisecond,
+		time.Duration(cfg.Health.TimeoutMs)*time.Millisecond,
+	)
+	healthRegistry.Register("postgres", pgClient.Ping)
+	healthRegistry.Register("kafka", func(ctx context.Context) error {
+		return kafka.Ping(ctx, cfg.Kafka)
+	})
+	healthRegistry.Register("consumer", srvc.ConsumerHeartbeat().Check(
+		time.Duration(cfg.Health.MaxHeartbeatAgeMs)*time.Millisecond,
+	))
+	if redisCacheAdapter != nil {
+		healthRegistry.Register("redis", redisCacheAdapter.Ping)
+	}
+	healthRegistry.Register("migrations", migrated.Check())
+	healthRegistry.Register("cache_warmup", cacheWarmedUp.Check())
+	healthHandler := handlers.NewHealthHandler(healthRegistry)
+
+	accessLog := middlewares.LogMiddleware(
+		cfg.AccessLog.Format,
+		time.Duration(cfg.AccessLog.SlowThresholdMs)*time.Millisecond,
 	)
 
-	postgresAdapter := storage.NewPostgresAdapter(pgClient)
-	kafkaAdapter := broker.NewKafkaConsumerAdapter(consumer)
-	inMemoryCacheAdapter := cache.NewInMemoryCacheAdapter(inMemoryCache)
-	srvc := service.New(inMemoryCacheAdapter, kafkaAdapter, postgresAdapter)
-	handler := handlers.NewHandler(srvc)
 	app := fiber.New()
 
 	app.Use(cors.New(cors.Config{
-		AllowMethods: "GET, POST, HEAD, PUT, DELETE, PATCH, OPTIONS",
-		AllowHeaders: "Content-Type",
-	}), middlewares.LogMiddleware())
+		AllowMethods:  "GET, POST, HEAD, PUT, DELETE, PATCH, OPTIONS",
+		AllowHeaders:  "Content-Type, " + schemas.CallerHeader + ", " + schemas.RequestIDHeader,
+		ExposeHeaders: schemas.CacheStatusHeader + ", " + schemas.RequestIDHeader,
+	}), accessLog)
 
 	app.Get("/ping", handlers.Ping)
+	app.Get("/healthz/live", healthHandler.Live)
+	app.Get("/healthz/ready", healthHandler.Ready)
 	apiV1 := app.Group("/api/v1")
 	apiV1.Get("/orders/:id", handler.GetOrderByID)
 
+	// operational endpoints are served on their own listener, off the public API port
+	adminApp := fiber.New(fiber.Config{DisableStartupMessage: true})
+	adminApp.Use(accessLog, pprof.New())
+
+	admin := adminApp.Group("/admin")
+	admin.Get("/log-level", adminHandler.GetLogLevel)
+	admin.Put("/log-level", adminHandler.SetLogLevel)
+	admin.Get("/audit", adminHandler.GetAuditRecords)
+	admin.Get("/cache/stats", adminHandler.GetCacheStats)
+	admin.Get("/cache/keys", adminHandler.GetCacheKeys)
+	admin.Get("/cache/entries/:key", adminHandler.GetCacheEntry)
+	admin.Delete("/cache/entries/:key", adminHandler.DeleteCacheEntry)
+	admin.Delete("/cache", adminHandler.PurgeCache)
+	admin.Get("/config", adminHandler.GetConfig)
+	admin.Get("/build-info", adminHandler.GetBuildInfo)
+	admin.Get("/consumer", adminHandler.GetConsumerState)
+
 	go func() {
 		if err = app.Listen(fmt.Sprintf(":%d", appCfg.Port)); err != nil {
 			log.Fatalf("failed to start app: %v", err)
 		}
 	}()
 
+	go func() {
+		if err := adminApp.Listen(fmt.Sprintf("%s:%d", cfg.Admin.Host, cfg.Admin.Port)); err != nil {
+			log.Fatalf("failed to start admin server: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := srvc.WarmUpCache(ctx, appCfg.CacheWarmUpSize); err != nil {
+			logger.Error(ctx, "failed to warm up cache", zap.Error(err))
+		}
+		cacheWarmedUp.Set()
+	}()
+
+	go func() {
+		if err := waitForInvalidations(ctx); err != nil {
+			logger.Error(ctx, "failed to join invalidation consumer group", zap.Error(err))
+			return
+		}
+		if err := srvc.LoadKnownOrders(ctx); err != nil {
+			logger.Error(ctx, "failed to load known orders filter", zap.Error(err))
+		}
+	}()
+
+	auditDone := make(chan struct{})
+	go func() {
+		defer close(auditDone)
+		auditor.Run(ctx)
+	}()
+
+	go func() {
+		if err := srvc.HandleInvalidations(ctx); err != nil {
+			logger.Error(ctx, "failed to handle cache invalidations", zap.Error(err))
+		}
+	}()
+
+	if partitionMaintainer != nil {
+		go partitionMaintainer.Run(ctx)
+	}
+
+	if tieredCacheAdapter != nil {
+		go func() {
+			if err := tieredCacheAdapter.ListenInvalidations(ctx); err != nil {
+				logger.Error(ctx, "failed to listen for cache invalidations", zap.Error(err))
+			}
+		}()
+	}
+
 	go srvc.HandleOrdersEvents(ctx, appCfg.BatchSize,
 		time.Second*time.Duration(appCfg.FlushTimeout),
 	)
-	inMemoryCache.StartCleanup(ctx, time.Duration(cacheCfg.CleanupInterval)*time.Minute)
+	if inMemoryCache != nil {
+		inMemoryCache.StartCleanup(ctx, time.Duration(cacheCfg.CleanupInterval)*time.Minute)
+	}
+	if notFoundCache != nil {
+		notFoundCache.StartCleanup(ctx, time.Duration(cacheCfg.CleanupInterval)*time.Minute)
+	}
 
 	<-ctx.Done()
 	err = app.Shutdown()
 	if err != nil {
 		logger.Fatal(ctx, "failed to shutdown server", zap.Error(err))
 	}
+	err = adminApp.Shutdown()
+	if err != nil {
+		logger.Fatal(ctx, "failed to shutdown admin server", zap.Error(err))
+	}
+	if inMemoryCache != nil && cacheCfg.SnapshotPath != "" {
+		if err = lrucache.SaveSnapshot(cacheCfg.SnapshotPath, inMemoryCache); err != nil {
+			logger.Error(ctx, "failed to save cache snapshot", zap.Error(err))
+		} else {
+			logger.Info(ctx, "cache snapshot saved", zap.Int("count", inMemoryCache.Len()))
+		}
+	}
+	<-auditDone
 	logger.Info(ctx, "server stopped")
 }
+
+// newInMemoryOrderCache creates the in-memory order cache and restores its snapshot.
+// notFound is nil if the negative caching is disabled
+func newInMemoryOrderCache(ctx context.Context, cfg config.Config, ttl cache.OrderTTL) (
+	*cache.InMemoryCacheAdapter, lrucache.Cache[string, *models.Order], *lrucache.InMemoryCache[string, struct{}], error,
+) {
+	cacheCfg := cfg.Cache
+	client, err := lrucache.NewFromConfig[string, *models.Order](cacheCfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cacheCfg.MaxCostMB > 0 {
+		client.SetMaxCost(int64(cacheCfg.MaxCostMB)<<20, cache.OrderCost)
+	}
+	if cacheCfg.SnapshotPath != "" {
+		restored, err := lrucache.LoadSnapshot(cacheCfg.SnapshotPath, client)
+		if err != nil {
+			logger.Warn(ctx, "ignoring cache snapshot", zap.Error(err))
+		} else {
+			logger.Info(ctx, "cache snapshot restored", zap.Int("count", restored))
+		}
+	}
+	cacheCtx := logger.WithComponent(ctx, "cache")
+	client.OnEvict(func(key string, _ *models.Order, reason lrucache.EvictionReason) {
+		logger.Debug(cacheCtx, "cache entry evicted",
+			zap.String("order_uid", key), zap.Stringer("reason", reason))
+	})
+
+	adapter := cache.NewInMemoryCacheAdapter(client, ttl)
+	var notFound *lrucache.InMemoryCache[string, struct{}]
+	if cfg.NotFound.CacheEnabled {
+		notFoundTTL := time.Duration(cfg.NotFound.CacheTTLSec) * time.Second
+		notFound = lrucache.NewInMemoryCache[string, struct{}](cfg.NotFound.CacheCapacity, notFoundTTL)
+		adapter.WithNotFoundCache(notFound, notFoundTTL)
+	}
+
+	return adapter, client, notFound, nil
+}
+
+// newRedisOrderCache connects to Redis and creates the order cache kept there,
+// a nil ttl keeps orders for OrderCache.RemoteTTLMinutes
+func newRedisOrderCache(ctx context.Context, cfg config.Config, ttl cache.OrderTTL) (
+	*cache.RedisCacheAdapter, *goredis.Client, error,
+) {
+	codec, err := cache.NewCodec(cfg.OrderCache.Codec)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := redis.New(ctx, cfg.Redis)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ttl == nil {
+		ttl = cache.FixedTTL(time.Duration(cfg.OrderCache.RemoteTTLMinutes) * time.Minute)
+	}
+	adapter := cache.NewRedisCacheAdapter(client, codec, cfg.OrderCache.KeyPrefix, ttl).
+		WithLoadTimeout(time.Duration(cfg.Cache.LoadTimeoutMs) * time.Millisecond)
+	if cfg.NotFound.CacheEnabled {
+		adapter.WithNotFoundTTL(time.Duration(cfg.NotFound.CacheTTLSec) * time.Second)
+	}
+
+	return adapter, client, nil
+}
+
+// reloadLogLevelsOnSIGHUP re-reads the config on SIGHUP and applies its log levels
+func reloadLogLevelsOnSIGHUP(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			cfg, err := config.New()
+			if err != nil {
+				logger.Error(ctx, "failed to reload config", zap.Error(err))
+				continue
+			}
+			if err = logger.SetLevel(cfg.Logger.Level); err != nil {
+				logger.Error(ctx, "failed to reload log level", zap.Error(err))
+				continue
+			}
+			if err = logger.SetComponentLevels(cfg.Logger.ComponentLevels); err != nil {
+				logger.Error(ctx, "failed to reload component log levels", zap.Error(err))
+				continue
+			}
+			logger.Info(ctx, "reloaded log levels",
+				zap.String("level", cfg.Logger.Level),
+				zap.Any("components", cfg.Logger.ComponentLevels),
+			)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Command cachesim replays a recorded access trace against every eviction policy
+// of lru-cache and prints their hit ratios, to pick CACHE_POLICY and CACHE_CAPACITY.
+//
+// The trace is a file with one key per line. Order reads can be extracted from the JSON access log:
+//
+//	jq -r 'select(.route == "/api/v1/orders/:id") | .path | split("/") | last' app.log > trace.txt
+//	go run ./cmd/cachesim -trace trace.txt -capacity 1000,10000
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	lrucache "github.com/jaam8/wb_tech_school_l0/pkg/lru-cache"
+)
+
+func main() {
+	tracePath := flag.String("trace", "-", "trace file with one key per line, - for stdin")
+	capacities := flag.String("capacity", "1000", "comma separated cache capacities")
+	policies := flag.String("policy", "lru,lfu,tinylfu", "comma separated eviction policies")
+	flag.Parse()
+
+	caps, err := parseCapacities(*capacities)
+	if err != nil {
+		log.Fatalf("invalid capacity: %v", err)
+	}
+
+	var ps []lrucache.Policy
+	for _, name := range strings.Split(*policies, ",") {
+		p, err := lrucache.ParsePolicy(strings.TrimSpace(name))
+		if err != nil {
+			log.Fatalf("invalid policy: %v", err)
+		}
+		ps = append(ps, p)
+	}
+
+	trace, err := readTrace(*tracePath)
+	if err != nil {
+		log.Fatalf("failed to read trace: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "policy\tcapacity\trequests\thits\thit ratio\t")
+	for _, c := range caps {
+		for _, p := range ps {
+			r := lrucache.Simulate(p, c, trace)
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f%%\t\n", r.Policy, r.Capacity, r.Requests, r.Hits, r.HitRatio()*100)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		log.Fatalf("failed to write results: %v", err)
+	}
+}
+
+func parseCapacities(s string) ([]int, error) {
+	var caps []int
+	for _, field := range strings.Split(s, ",") {
+		c, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		if c <= 0 {
+			return nil, fmt.Errorf("capacity must be positive, got %d", c)
+		}
+		caps = append(caps, c)
+	}
+	return caps, nil
+}
+
+func readTrace(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var trace []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			trace = append(trace, key)
+		}
+	}
+
+	return trace, scanner.Err()
+}
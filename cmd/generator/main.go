@@ -23,8 +23,10 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	ctx = context.WithValue(ctx, logger.KeyForLogLevel, cfg.LogLevel)
-	ctx, _ = logger.New(ctx)
+	if _, err = logger.Init(cfg.Logger); err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	defer logger.Sync()
 
 	kafkaCfg := cfg.Kafka
 	appCfg := cfg.Service
@@ -15,6 +15,329 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/audit": {
+            "get": {
+                "description": "Returns the newest audit records filtered by order, caller and time range",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "query audit trail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "order uid",
+                        "name": "order_uid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "caller IP",
+                        "name": "caller",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "caller identity sent in X-Caller-ID, not verified",
+                        "name": "claimed_caller",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the range, RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the range, RFC3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "max number of records",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/build-info": {
+            "get": {
+                "description": "Returns the Go version, module version and VCS revision the binary was built from",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "build info",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.BuildInfoResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache": {
+            "delete": {
+                "description": "Removes every order from the cache. For the tiered cache the shared Redis tier is emptied,\nother instances keep serving their local copies until these expire",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "purge cache",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/admin/cache/entries/{key}": {
+            "get": {
+                "description": "Returns a cached order with its expiration time, without touching the LRU order.\nThe read is audited like GET /api/v1/order/{id}",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "get cache entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "order uid",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CacheEntry"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "the read could not be audited",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes a single order from the cache",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "delete cache entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "order uid",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache/keys": {
+            "get": {
+                "description": "Returns the ids of cached orders, the most recently used first for the in-memory LRU cache.\nExpired orders not cleaned up yet are listed too",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "list cache keys",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "max number of keys",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.CacheKeysResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache/stats": {
+            "get": {
+                "description": "Returns the order cache size and hit, miss, eviction and expiration counters.\nFor the tiered cache they are the counters of the local tier, the remote one is reported under remote",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "cache stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CacheStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/config": {
+            "get": {
+                "description": "Returns the config the service runs with, secrets are redacted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "effective config",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.Config"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/consumer": {
+            "get": {
+                "description": "Returns the Kafka reader offsets and the size of the batch not yet saved to storage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "consumer state",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConsumerState"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/log-level": {
+            "get": {
+                "description": "Returns the process-wide log level and per-component overrides",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "get log level",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.LogLevelResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Changes the process-wide log level, or the level of a single component if it is set.\nAn empty level with a component resets the component override",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "set log level",
+                "parameters": [
+                    {
+                        "description": "new level",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.LogLevelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.LogLevelResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/order/{id}": {
             "get": {
                 "description": "returns an order by its order_uid",
@@ -33,6 +356,12 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.Order"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "hit, miss, remote if found in the shared cache only, or stale if the order expired in cache and is being reloaded"
+                            }
                         }
                     },
                     "400": {
@@ -45,6 +374,12 @@ const docTemplate = `{
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/schemas.ErrorResponse"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "miss, or negative if the order is known to be missing"
+                            }
                         }
                     },
                     "500": {
@@ -52,35 +387,616 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/schemas.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "the read could not be audited",
+                        "schema": {
+                            "$ref": "#/definitions/schemas.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz/live": {
+            "get": {
+                "description": "Returns 200 while the process is running, dependencies are not checked",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz/ready": {
+            "get": {
+                "description": "Runs the registered dependency checks and returns per-check details",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/ping": {
+            "get": {
+                "description": "Returns \"pong\" if the service is alive",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "health checker",
+                "responses": {
+                    "200": {
+                        "description": "pong",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "config.AccessLogConfig": {
+            "type": "object",
+            "properties": {
+                "format": {
+                    "description": "json, or clf for the combined log format in the clf field",
+                    "type": "string"
+                },
+                "slow_threshold_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.AdminConfig": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.AppConfig": {
+            "type": "object",
+            "properties": {
+                "base_retry_delay": {
+                    "type": "integer"
+                },
+                "batch_size": {
+                    "type": "integer"
+                },
+                "cache_warmup_size": {
+                    "type": "integer"
+                },
+                "copy_min_batch": {
+                    "description": "batches this large are written with COPY, 0 disables it",
+                    "type": "integer"
+                },
+                "flush_timeout": {
+                    "type": "integer"
+                },
+                "kafka_group_id": {
+                    "type": "string"
+                },
+                "kafka_num_partitions": {
+                    "type": "integer"
+                },
+                "kafka_replication_factor": {
+                    "type": "integer"
+                },
+                "kafka_topic": {
+                    "type": "string"
+                },
+                "max_retries": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.AuditConfig": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer"
+                },
+                "buffer_size": {
+                    "type": "integer"
+                },
+                "flush_interval_ms": {
+                    "type": "integer"
+                },
+                "record_timeout_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.Config": {
+            "type": "object",
+            "properties": {
+                "access_log": {
+                    "$ref": "#/definitions/config.AccessLogConfig"
+                },
+                "admin": {
+                    "$ref": "#/definitions/config.AdminConfig"
+                },
+                "audit": {
+                    "$ref": "#/definitions/config.AuditConfig"
+                },
+                "cache": {
+                    "$ref": "#/definitions/lrucache.Config"
+                },
+                "fake_orders_count": {
+                    "type": "integer"
+                },
+                "health": {
+                    "$ref": "#/definitions/health.Config"
+                },
+                "invalidation": {
+                    "$ref": "#/definitions/config.InvalidationConfig"
+                },
+                "kafka": {
+                    "$ref": "#/definitions/kafka.Config"
+                },
+                "log": {
+                    "$ref": "#/definitions/logger.Config"
+                },
+                "migrations_path": {
+                    "type": "string"
+                },
+                "not_found": {
+                    "$ref": "#/definitions/config.NotFoundConfig"
+                },
+                "order_cache": {
+                    "$ref": "#/definitions/config.OrderCacheConfig"
+                },
+                "order_ttl": {
+                    "$ref": "#/definitions/config.OrderTTLConfig"
+                },
+                "partitions": {
+                    "$ref": "#/definitions/config.PartitionsConfig"
+                },
+                "postgres": {
+                    "$ref": "#/definitions/postgres.Config"
+                },
+                "redis": {
+                    "$ref": "#/definitions/redis.Config"
+                },
+                "service": {
+                    "$ref": "#/definitions/config.AppConfig"
+                }
+            }
+        },
+        "config.InvalidationConfig": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "group_prefix": {
+                    "type": "string"
+                },
+                "refresh": {
+                    "description": "Refresh reloads invalidated orders from the storage instead of dropping them,\nat the cost of a storage query per order on every instance",
+                    "type": "boolean"
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.NotFoundConfig": {
+            "type": "object",
+            "properties": {
+                "bloom_capacity": {
+                    "description": "expected number of orders",
+                    "type": "integer"
+                },
+                "bloom_enabled": {
+                    "type": "boolean"
+                },
+                "bloom_fp_rate": {
+                    "type": "number"
+                },
+                "cache_capacity": {
+                    "type": "integer"
+                },
+                "cache_enabled": {
+                    "type": "boolean"
+                },
+                "cache_ttl_sec": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.OrderCacheConfig": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "description": "memory, redis or tiered",
+                    "type": "string"
+                },
+                "codec": {
+                    "description": "json or msgpack",
+                    "type": "string"
+                },
+                "key_prefix": {
+                    "type": "string"
+                },
+                "remote_ttl_minutes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.OrderTTLConfig": {
+            "type": "object",
+            "properties": {
+                "cold_minutes": {
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "hot_minutes": {
+                    "type": "integer"
+                },
+                "recent_days": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.PartitionsConfig": {
+            "type": "object",
+            "properties": {
+                "ahead_months": {
+                    "type": "integer"
+                },
+                "archive_dir": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval_minutes": {
+                    "type": "integer"
+                },
+                "retention_action": {
+                    "description": "detach or drop",
+                    "type": "string"
+                },
+                "retention_months": {
+                    "description": "0 keeps every partition",
+                    "type": "integer"
+                }
+            }
+        },
+        "health.Config": {
+            "type": "object",
+            "properties": {
+                "cache_ttl_ms": {
+                    "type": "integer"
+                },
+                "max_heartbeat_age_ms": {
+                    "type": "integer"
+                },
+                "timeout_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Result"
+                    }
+                },
+                "status": {
+                    "$ref": "#/definitions/health.Status"
+                }
+            }
+        },
+        "health.Result": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/health.Status"
+                }
+            }
+        },
+        "health.Status": {
+            "type": "string",
+            "enum": [
+                "up",
+                "down"
+            ],
+            "x-enum-varnames": [
+                "StatusUp",
+                "StatusDown"
+            ]
+        },
+        "kafka.Config": {
+            "type": "object",
+            "properties": {
+                "brokers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "commit_interval_ms": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "max_bytes": {
+                    "description": "1MB",
+                    "type": "integer"
+                },
+                "max_wait_ms": {
+                    "type": "integer"
+                },
+                "min_bytes": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
                 }
             }
         },
-        "/ping": {
-            "get": {
-                "description": "Returns \"pong\" if the service is alive",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "health"
-                ],
-                "summary": "health checker",
-                "responses": {
-                    "200": {
-                        "description": "pong",
-                        "schema": {
-                            "type": "string"
-                        }
+        "logger.Config": {
+            "type": "object",
+            "properties": {
+                "component_levels": {
+                    "description": "e.g. consumer:debug,storage:warn",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
                     }
+                },
+                "encoding": {
+                    "type": "string"
+                },
+                "level": {
+                    "type": "string"
+                },
+                "sampling_initial": {
+                    "description": "0 disables sampling",
+                    "type": "integer"
+                },
+                "sampling_thereafter": {
+                    "type": "integer"
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "lrucache.Config": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "type": "integer"
+                },
+                "cleanup_interval": {
+                    "type": "integer"
+                },
+                "load_timeout_ms": {
+                    "description": "zero for no timeout",
+                    "type": "integer"
+                },
+                "max_cost_mb": {
+                    "description": "MaxCostMB bounds the estimated size of cached values, zero disables it.\nSet Capacity to zero to bound the cache by size only",
+                    "type": "integer"
+                },
+                "policy": {
+                    "description": "lru, lfu or tinylfu",
+                    "type": "string"
+                },
+                "shards": {
+                    "description": "more than one enables ShardedCache",
+                    "type": "integer"
+                },
+                "sliding": {
+                    "type": "boolean"
+                },
+                "snapshot_path": {
+                    "description": "empty disables snapshots",
+                    "type": "string"
+                },
+                "stale_grace_sec": {
+                    "description": "zero disables stale-while-revalidate",
+                    "type": "integer"
+                },
+                "ttl": {
+                    "description": "minutes, negative for no expiry",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuditRecord": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "caller": {
+                    "type": "string"
+                },
+                "claimed_caller": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "order_uid": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "route": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CacheEntry": {
+            "type": "object",
+            "properties": {
+                "expired": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "description": "zero if the entry never expires",
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "value": {
+                    "$ref": "#/definitions/models.Order"
+                }
+            }
+        },
+        "models.CacheStats": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "type": "integer"
+                },
+                "cost": {
+                    "description": "estimated bytes held by cached orders",
+                    "type": "integer"
+                },
+                "evictions": {
+                    "type": "integer"
+                },
+                "expirations": {
+                    "type": "integer"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "len": {
+                    "type": "integer"
+                },
+                "max_cost": {
+                    "description": "zero if the cache is not bounded by size",
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "remote": {
+                    "description": "shared tier of the tiered cache, counted by this instance",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CacheStats"
+                        }
+                    ]
+                },
+                "stale_hits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ConsumerState": {
+            "type": "object",
+            "properties": {
+                "broker": {
+                    "$ref": "#/definitions/models.ConsumerStats"
+                },
+                "in_flight_batch": {
+                    "type": "integer"
+                },
+                "last_heartbeat": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConsumerStats": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "integer"
+                },
+                "lag": {
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "partition": {
+                    "type": "string"
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Delivery": {
             "type": "object",
             "required": [
@@ -324,6 +1240,111 @@ const docTemplate = `{
                 }
             }
         },
+        "postgres.Config": {
+            "type": "object",
+            "properties": {
+                "db": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "max_conns": {
+                    "type": "integer"
+                },
+                "min_conns": {
+                    "type": "integer"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "redis.Config": {
+            "type": "object",
+            "properties": {
+                "db": {
+                    "type": "integer"
+                },
+                "dial_timeout_ms": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "min_idle_conns": {
+                    "type": "integer"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "pool_size": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "read_timeout_ms": {
+                    "type": "integer"
+                },
+                "write_timeout_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "schemas.BuildInfoResponse": {
+            "type": "object",
+            "properties": {
+                "go_version": {
+                    "type": "string",
+                    "example": "go1.25.0"
+                },
+                "modified": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "path": {
+                    "type": "string",
+                    "example": "github.com/jaam8/wb_tech_school_l0/cmd/app"
+                },
+                "revision": {
+                    "type": "string",
+                    "example": "6c4466e"
+                },
+                "time": {
+                    "type": "string",
+                    "example": "2025-10-01T12:00:00Z"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "(devel)"
+                }
+            }
+        },
+        "schemas.CacheKeysResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "b563feb7b2b84b6test"
+                    ]
+                }
+            }
+        },
         "schemas.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -336,6 +1357,34 @@ const docTemplate = `{
                     "example": false
                 }
             }
+        },
+        "schemas.LogLevelRequest": {
+            "type": "object",
+            "properties": {
+                "component": {
+                    "type": "string",
+                    "example": "consumer"
+                },
+                "level": {
+                    "type": "string",
+                    "example": "debug"
+                }
+            }
+        },
+        "schemas.LogLevelResponse": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "level": {
+                    "type": "string",
+                    "example": "info"
+                }
+            }
         }
     }
 }`
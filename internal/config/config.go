@@ -3,34 +3,133 @@ package config
 import (
 	"fmt"
 	"log"
+	"reflect"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/jaam8/wb_tech_school_l0/pkg/health"
 	"github.com/jaam8/wb_tech_school_l0/pkg/kafka"
+	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
 	lrucache "github.com/jaam8/wb_tech_school_l0/pkg/lru-cache"
 	"github.com/jaam8/wb_tech_school_l0/pkg/postgres"
+	"github.com/jaam8/wb_tech_school_l0/pkg/redis"
 )
 
 type Config struct {
-	Kafka    kafka.Config    `env-prefix:"KAFKA_"    yaml:"kafka"`
-	Cache    lrucache.Config `env-prefix:"CACHE_"    yaml:"cache"`
-	Postgres postgres.Config `env-prefix:"POSTGRES_" yaml:"postgres"`
-	Service  AppConfig       `env-prefix:"APP_"      yaml:"service"`
+	Kafka    kafka.Config    `env-prefix:"KAFKA_"     json:"kafka"     yaml:"kafka"`
+	Cache    lrucache.Config `env-prefix:"CACHE_"     json:"cache"     yaml:"cache"`
+	OrderTTL OrderTTLConfig  `env-prefix:"ORDER_TTL_" json:"order_ttl" yaml:"order_ttl"`
+	NotFound NotFoundConfig  `env-prefix:"NOT_FOUND_" json:"not_found" yaml:"not_found"`
+	Postgres postgres.Config `env-prefix:"POSTGRES_"  json:"postgres"  yaml:"postgres"`
+	Redis    redis.Config    `env-prefix:"REDIS_"     json:"redis"     yaml:"redis"`
+	Health   health.Config   `env-prefix:"HEALTH_"    json:"health"    yaml:"health"`
+	Logger   logger.Config   `env-prefix:"LOG_"       json:"log"       yaml:"log"`
+	Service  AppConfig       `env-prefix:"APP_"       json:"service"   yaml:"service"`
+	Audit    AuditConfig     `env-prefix:"AUDIT_"     json:"audit"     yaml:"audit"`
+	Admin    AdminConfig     `env-prefix:"ADMIN_"     json:"admin"     yaml:"admin"`
 
-	LogLevel        string `env:"LOG_LEVEL"         env-default:"info"         yaml:"log_level"`
-	MigrationsPath  string `env:"MIGRATIONS_PATH"   env-default:"./migrations" yaml:"migrations_path"`
-	FakeOrdersCount int    `env:"FAKE_ORDERS_COUNT" env-default:"10"           yaml:"fake_orders_count"`
+	OrderCache   OrderCacheConfig   `env-prefix:"ORDER_CACHE_"  json:"order_cache"  yaml:"order_cache"`
+	Invalidation InvalidationConfig `env-prefix:"INVALIDATION_" json:"invalidation" yaml:"invalidation"`
+	Partitions   PartitionsConfig   `env-prefix:"PARTITIONS_"   json:"partitions"   yaml:"partitions"`
+
+	AccessLog AccessLogConfig `env-prefix:"ACCESS_LOG_" json:"access_log" yaml:"access_log"`
+
+	MigrationsPath  string `env:"MIGRATIONS_PATH"   env-default:"./migrations" json:"migrations_path"   yaml:"migrations_path"`
+	FakeOrdersCount int    `env:"FAKE_ORDERS_COUNT" env-default:"10"           json:"fake_orders_count" yaml:"fake_orders_count"`
 }
 
 type AppConfig struct {
-	Port                   uint16 `env:"PORT"                     env-default:"8080"      yaml:"port"`
-	KafkaTopic             string `env:"KAFKA_TOPIC"              yaml:"kafka_topic"`
-	KafkaGroupID           string `env:"KAFKA_GROUP_ID"           yaml:"kafka_group_id"`
-	BatchSize              int    `env:"BATCH_SIZE"               env-default:"1"         yaml:"batch_size"`
-	FlushTimeout           int    `env:"FLUSH_TIMEOUT"            env-default:"1"         yaml:"flush_timeout"`
-	KafkaNumPartitions     int    `env:"KAFKA_NUM_PARTITIONS"     env-default:"1"         yaml:"kafka_num_partitions"`
-	KafkaReplicationFactor int    `env:"KAFKA_REPLICATION_FACTOR" env-default:"1"         yaml:"kafka_replication_factor"`
-	MaxRetries             int    `env:"MAX_RETRIES"              env-default:"5"         yaml:"max_retries"`
-	BaseRetryDelay         int    `env:"BASE_RETRY_DELAY"         yaml:"base_retry_delay"`
+	Port                   uint16 `env:"PORT"                     env-default:"8080"      json:"port"                     yaml:"port"`
+	KafkaTopic             string `env:"KAFKA_TOPIC"              json:"kafka_topic"      yaml:"kafka_topic"`
+	KafkaGroupID           string `env:"KAFKA_GROUP_ID"           json:"kafka_group_id"   yaml:"kafka_group_id"`
+	BatchSize              int    `env:"BATCH_SIZE"               env-default:"1"         json:"batch_size"               yaml:"batch_size"`
+	FlushTimeout           int    `env:"FLUSH_TIMEOUT"            env-default:"1"         json:"flush_timeout"            yaml:"flush_timeout"`
+	KafkaNumPartitions     int    `env:"KAFKA_NUM_PARTITIONS"     env-default:"1"         json:"kafka_num_partitions"     yaml:"kafka_num_partitions"`
+	KafkaReplicationFactor int    `env:"KAFKA_REPLICATION_FACTOR" env-default:"1"         json:"kafka_replication_factor" yaml:"kafka_replication_factor"`
+	MaxRetries             int    `env:"MAX_RETRIES"              env-default:"5"         json:"max_retries"              yaml:"max_retries"`
+	BaseRetryDelay         int    `env:"BASE_RETRY_DELAY"         json:"base_retry_delay" yaml:"base_retry_delay"`
+	CacheWarmUpSize        int    `env:"CACHE_WARMUP_SIZE"        env-default:"0"         json:"cache_warmup_size"        yaml:"cache_warmup_size"`
+	CopyMinBatch           int    `env:"COPY_MIN_BATCH"           env-default:"0"         json:"copy_min_batch"           yaml:"copy_min_batch"` // batches this large are written with COPY, 0 disables it
+}
+
+// OrderTTLConfig sets the cache TTL of an order by its age, see cache.AgeBasedTTL
+type OrderTTLConfig struct {
+	Enabled     bool `env:"ENABLED"      env-default:"false" json:"enabled"      yaml:"enabled"`
+	HotMinutes  int  `env:"HOT_MINUTES"  env-default:"60"    json:"hot_minutes"  yaml:"hot_minutes"`
+	ColdMinutes int  `env:"COLD_MINUTES" env-default:"5"     json:"cold_minutes" yaml:"cold_minutes"`
+	RecentDays  int  `env:"RECENT_DAYS"  env-default:"30"    json:"recent_days"  yaml:"recent_days"`
+}
+
+// NotFoundConfig sets up the negative caching of unknown order ids and the Bloom filter
+// of known ones, ids missing from the filter are still looked up
+type NotFoundConfig struct {
+	CacheEnabled  bool    `env:"CACHE_ENABLED"  env-default:"true"    json:"cache_enabled"  yaml:"cache_enabled"`
+	CacheCapacity int     `env:"CACHE_CAPACITY" env-default:"10000"   json:"cache_capacity" yaml:"cache_capacity"`
+	CacheTTLSec   int     `env:"CACHE_TTL_SEC"  env-default:"30"      json:"cache_ttl_sec"  yaml:"cache_ttl_sec"`
+	BloomEnabled  bool    `env:"BLOOM_ENABLED"  env-default:"false"   json:"bloom_enabled"  yaml:"bloom_enabled"`
+	BloomCapacity int     `env:"BLOOM_CAPACITY" env-default:"1000000" json:"bloom_capacity" yaml:"bloom_capacity"` // expected number of orders
+	BloomFPRate   float64 `env:"BLOOM_FP_RATE"  env-default:"0.01"    json:"bloom_fp_rate"  yaml:"bloom_fp_rate"`
+}
+
+// OrderCacheConfig selects where orders are cached: in the memory of the instance
+// with the settings of Cache, in Redis shared by all instances, or in both tiers
+type OrderCacheConfig struct {
+	Backend          string `env:"BACKEND"            env-default:"memory" json:"backend"            yaml:"backend"` // memory, redis or tiered
+	KeyPrefix        string `env:"KEY_PREFIX"         env-default:"wb_l0:" json:"key_prefix"         yaml:"key_prefix"`
+	Codec            string `env:"CODEC"              env-default:"json"   json:"codec"              yaml:"codec"` // json or msgpack
+	RemoteTTLMinutes int    `env:"REMOTE_TTL_MINUTES" env-default:"60"     json:"remote_ttl_minutes" yaml:"remote_ttl_minutes"`
+}
+
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+	// CacheBackendTiered keeps orders in memory for Cache.TTL in front of Redis,
+	// where they are kept for RemoteTTLMinutes or by OrderTTL
+	CacheBackendTiered = "tiered"
+)
+
+// InvalidationConfig broadcasts the ids of stored orders to all instances over a compacted Kafka topic,
+// every instance reads it in a consumer group of its own named GroupPrefix followed by a random suffix
+type InvalidationConfig struct {
+	Enabled     bool   `env:"ENABLED"      env-default:"false"               json:"enabled"      yaml:"enabled"`
+	Topic       string `env:"TOPIC"        env-default:"orders-invalidation" json:"topic"        yaml:"topic"`
+	GroupPrefix string `env:"GROUP_PREFIX" env-default:"orders-invalidation" json:"group_prefix" yaml:"group_prefix"`
+	// Refresh reloads invalidated orders from the storage instead of dropping them,
+	// at the cost of a storage query per order on every instance
+	Refresh bool `env:"REFRESH" env-default:"false" json:"refresh" yaml:"refresh"`
+}
+
+const (
+	RetentionDetach = "detach"
+	RetentionDrop   = "drop"
+)
+
+// PartitionsConfig maintains the monthly partitions of the orders, see service.PartitionMaintainer.
+// Partitions older than RetentionMonths are detached or dropped by RetentionAction,
+// after being exported to ArchiveDir if it is set
+type PartitionsConfig struct {
+	Enabled         bool   `env:"ENABLED"          env-default:"true" json:"enabled"          yaml:"enabled"`
+	AheadMonths     int    `env:"AHEAD_MONTHS"     env-default:"3"    json:"ahead_months"     yaml:"ahead_months"`
+	RetentionMonths int    `env:"RETENTION_MONTHS" env-default:"0"    json:"retention_months" yaml:"retention_months"` // 0 keeps every partition
+	RetentionAction string `env:"RETENTION_ACTION" env-default:"drop" json:"retention_action" yaml:"retention_action"` // detach or drop
+	ArchiveDir      string `env:"ARCHIVE_DIR"      json:"archive_dir" yaml:"archive_dir"`
+	IntervalMinutes int    `env:"INTERVAL_MINUTES" env-default:"60"   json:"interval_minutes" yaml:"interval_minutes"`
+}
+
+type AuditConfig struct {
+	BufferSize      int `env:"BUFFER_SIZE"       env-default:"1024" json:"buffer_size"       yaml:"buffer_size"`
+	BatchSize       int `env:"BATCH_SIZE"        env-default:"100"  json:"batch_size"        yaml:"batch_size"`
+	FlushIntervalMs int `env:"FLUSH_INTERVAL_MS" env-default:"1000" json:"flush_interval_ms" yaml:"flush_interval_ms"`
+	RecordTimeoutMs int `env:"RECORD_TIMEOUT_MS" env-default:"100"  json:"record_timeout_ms" yaml:"record_timeout_ms"`
+}
+
+type AccessLogConfig struct {
+	Format          string `env:"FORMAT"            env-default:"json" json:"format"            yaml:"format"` // json, or clf for the combined log format in the clf field
+	SlowThresholdMs int    `env:"SLOW_THRESHOLD_MS" env-default:"500"  json:"slow_threshold_ms" yaml:"slow_threshold_ms"`
+}
+
+type AdminConfig struct {
+	Host string `env:"HOST" env-default:"127.0.0.1" json:"host" yaml:"host"`
+	Port uint16 `env:"PORT" env-default:"8081"      json:"port" yaml:"port"`
 }
 
 func New() (Config, error) {
@@ -45,3 +144,25 @@ func New() (Config, error) {
 
 	return cfg, nil
 }
+
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the config with every string field tagged secret:"true" replaced
+func (c Config) Redacted() Config {
+	redactSecrets(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redactSecrets(v reflect.Value) {
+	for i := range v.NumField() {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			redactSecrets(field)
+		case field.Kind() == reflect.String && v.Type().Field(i).Tag.Get("secret") == "true":
+			if field.String() != "" {
+				field.SetString(redacted)
+			}
+		}
+	}
+}
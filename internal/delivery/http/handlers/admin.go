@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jaam8/wb_tech_school_l0/internal/config"
+	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/schemas"
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jaam8/wb_tech_school_l0/internal/ports"
+	"github.com/jaam8/wb_tech_school_l0/internal/service"
+	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
+)
+
+const defaultCacheKeysLimit = 100
+
+type AdminHandler struct {
+	auditor *service.Auditor
+	cache   ports.CacheAdminAdapter
+	service *service.Service
+	config  config.Config
+}
+
+func NewAdminHandler(
+	a *service.Auditor,
+	cache ports.CacheAdminAdapter,
+	s *service.Service,
+	cfg config.Config,
+) *AdminHandler {
+	return &AdminHandler{
+		auditor: a,
+		cache:   cache,
+		service: s,
+		config:  cfg.Redacted(),
+	}
+}
+
+// GetLogLevel godoc
+// @Summary get log level
+// @Description Returns the process-wide log level and per-component overrides
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schemas.LogLevelResponse
+// @Router /admin/log-level [get]
+func (h *AdminHandler) GetLogLevel(c *fiber.Ctx) error {
+	level, components := logger.Levels()
+
+	return c.Status(http.StatusOK).JSON(schemas.LogLevelResponse{
+		Level:      level,
+		Components: components,
+	})
+}
+
+// SetLogLevel godoc
+// @Summary set log level
+// @Description Changes the process-wide log level, or the level of a single component if it is set.
+// @Description An empty level with a component resets the component override
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body schemas.LogLevelRequest true "new level"
+// @Success 200 {object} schemas.LogLevelResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Router /admin/log-level [put]
+func (h *AdminHandler) SetLogLevel(c *fiber.Ctx) error {
+	var req schemas.LogLevelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).
+			JSON(schemas.ErrorResponse{Error: "invalid request body"})
+	}
+
+	record := auditRecord(c, models.AuditActionLogLevelChange)
+	record.Result = models.AuditResultOK
+	record.Details = fmt.Sprintf("component=%q level=%q", req.Component, req.Level)
+	defer func() {
+		_ = h.auditor.Record(c.UserContext(), record)
+	}()
+
+	var err error
+	switch {
+	case req.Component != "" && req.Level == "":
+		logger.ResetComponentLevel(req.Component)
+	case req.Component != "":
+		err = logger.SetComponentLevel(req.Component, req.Level)
+	default:
+		err = logger.SetLevel(req.Level)
+	}
+	if err != nil {
+		record.Result = models.AuditResultError
+		return c.Status(http.StatusBadRequest).
+			JSON(schemas.ErrorResponse{Error: err.Error()})
+	}
+
+	return h.GetLogLevel(c)
+}
+
+// GetCacheStats godoc
+// @Summary cache stats
+// @Description Returns the order cache size and hit, miss, eviction and expiration counters.
+// @Description For the tiered cache they are the counters of the local tier, the remote one is reported under remote
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.CacheStats
+// @Router /admin/cache/stats [get]
+func (h *AdminHandler) GetCacheStats(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(h.cache.Stats())
+}
+
+// GetCacheKeys godoc
+// @Summary list cache keys
+// @Description Returns the ids of cached orders, the most recently used first for the in-memory LRU cache.
+// @Description Expired orders not cleaned up yet are listed too
+// @Tags admin
+// @Produce json
+// @Param limit query int false "max number of keys" default(100)
+// @Success 200 {object} schemas.CacheKeysResponse
+// @Router /admin/cache/keys [get]
+func (h *AdminHandler) GetCacheKeys(c *fiber.Ctx) error {
+	keys := h.cache.Keys(c.QueryInt("limit", defaultCacheKeysLimit))
+
+	return c.Status(http.StatusOK).JSON(schemas.CacheKeysResponse{
+		Keys:  keys,
+		Count: len(keys),
+	})
+}
+
+// GetCacheEntry godoc
+// @Summary get cache entry
+// @Description Returns a cached order with its expiration time, without touching the LRU order.
+// @Description The read is audited like GET /api/v1/order/{id}
+// @Tags admin
+// @Produce json
+// @Param key path string true "order uid"
+// @Success 200 {object} models.CacheEntry
+// @Failure 404 {object} schemas.ErrorResponse
+// @Failure 503 {object} schemas.ErrorResponse "the read could not be audited"
+// @Router /admin/cache/entries/{key} [get]
+func (h *AdminHandler) GetCacheEntry(c *fiber.Ctx) error {
+	record := auditRecord(c, models.AuditActionOrderRead)
+	record.OrderUID = c.Params("key")
+
+	entry, err := h.cache.PeekOrder(record.OrderUID)
+	if err != nil {
+		record.Result = models.AuditResultNotFound
+		_ = h.auditor.Record(c.UserContext(), record)
+		return c.Status(http.StatusNotFound).
+			JSON(schemas.ErrorResponse{Error: err.Error()})
+	}
+	record.Result = models.AuditResultOK
+	record.Fields = models.OrderFields
+
+	return sendAudited(c, h.auditor, record, entry)
+}
+
+// DeleteCacheEntry godoc
+// @Summary delete cache entry
+// @Description Removes a single order from the cache
+// @Tags admin
+// @Param key path string true "order uid"
+// @Success 204
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/cache/entries/{key} [delete]
+func (h *AdminHandler) DeleteCacheEntry(c *fiber.Ctx) error {
+	key := c.Params("key")
+	record := auditRecord(c, models.AuditActionCacheDelete)
+	record.OrderUID = key
+	record.Result = models.AuditResultOK
+	defer func() {
+		_ = h.auditor.Record(c.UserContext(), record)
+	}()
+
+	if err := h.cache.DeleteOrder(key); err != nil {
+		record.Result = models.AuditResultNotFound
+		return c.Status(http.StatusNotFound).
+			JSON(schemas.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// PurgeCache godoc
+// @Summary purge cache
+// @Description Removes every order from the cache. For the tiered cache the shared Redis tier is emptied,
+// @Description other instances keep serving their local copies until these expire
+// @Tags admin
+// @Success 204
+// @Router /admin/cache [delete]
+func (h *AdminHandler) PurgeCache(c *fiber.Ctx) error {
+	stats := h.cache.Stats()
+	h.cache.Purge()
+
+	record := auditRecord(c, models.AuditActionCachePurge)
+	record.Result = models.AuditResultOK
+	record.Details = fmt.Sprintf("purged=%d", stats.Len)
+	_ = h.auditor.Record(c.UserContext(), record)
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// GetConfig godoc
+// @Summary effective config
+// @Description Returns the config the service runs with, secrets are redacted
+// @Tags admin
+// @Produce json
+// @Success 200 {object} config.Config
+// @Router /admin/config [get]
+func (h *AdminHandler) GetConfig(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(h.config)
+}
+
+// GetBuildInfo godoc
+// @Summary build info
+// @Description Returns the Go version, module version and VCS revision the binary was built from
+// @Tags admin
+// @Produce json
+// @Success 200 {object} schemas.BuildInfoResponse
+// @Router /admin/build-info [get]
+func (h *AdminHandler) GetBuildInfo(c *fiber.Ctx) error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return c.Status(http.StatusOK).JSON(schemas.BuildInfoResponse{GoVersion: runtime.Version()})
+	}
+
+	resp := schemas.BuildInfoResponse{
+		GoVersion: info.GoVersion,
+		Path:      info.Path,
+		Version:   info.Main.Version,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			resp.Revision = setting.Value
+		case "vcs.time":
+			resp.Time = setting.Value
+		case "vcs.modified":
+			resp.Modified = setting.Value == "true"
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(resp)
+}
+
+// GetConsumerState godoc
+// @Summary consumer state
+// @Description Returns the Kafka reader offsets and the size of the batch not yet saved to storage
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.ConsumerState
+// @Router /admin/consumer [get]
+func (h *AdminHandler) GetConsumerState(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(h.service.ConsumerState())
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/schemas"
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+)
+
+// GetAuditRecords godoc
+// @Summary query audit trail
+// @Description Returns the newest audit records filtered by order, caller and time range
+// @Tags admin
+// @Produce json
+// @Param order_uid query string false "order uid"
+// @Param caller query string false "caller IP"
+// @Param claimed_caller query string false "caller identity sent in X-Caller-ID, not verified"
+// @Param from query string false "start of the range, RFC3339"
+// @Param to query string false "end of the range, RFC3339"
+// @Param limit query int false "max number of records" default(100)
+// @Success 200 {array} models.AuditRecord
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 500 {object} schemas.ErrorResponse
+// @Router /admin/audit [get]
+func (h *AdminHandler) GetAuditRecords(c *fiber.Ctx) error {
+	filter := models.AuditFilter{
+		OrderUID:      c.Query("order_uid"),
+		Caller:        c.Query("caller"),
+		ClaimedCaller: c.Query("claimed_caller"),
+		Limit:         c.QueryInt("limit"),
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return c.Status(http.StatusBadRequest).
+				JSON(schemas.ErrorResponse{Error: "invalid from, expected RFC3339"})
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return c.Status(http.StatusBadRequest).
+				JSON(schemas.ErrorResponse{Error: "invalid to, expected RFC3339"})
+		}
+	}
+
+	records, err := h.auditor.Query(c.UserContext(), filter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).
+			JSON(schemas.ErrorResponse{Error: errs.ErrInternalServerError.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(records)
+}
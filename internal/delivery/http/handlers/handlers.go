@@ -6,17 +6,20 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/schemas"
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
 	"github.com/jaam8/wb_tech_school_l0/internal/service"
 	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
 )
 
 type Handler struct {
 	service *service.Service
+	auditor *service.Auditor
 }
 
-func NewHandler(s *service.Service) *Handler {
+func NewHandler(s *service.Service, a *service.Auditor) *Handler {
 	return &Handler{
 		service: s,
+		auditor: a,
 	}
 }
 
@@ -27,9 +30,12 @@ func NewHandler(s *service.Service) *Handler {
 // @Accept json
 // @Produce json
 // @Success 200 {object} models.Order
+// @Header 200 {string} X-Cache "hit, miss, remote if found in the shared cache only, or stale if the order expired in cache and is being reloaded"
 // @Failure 400 {object} schemas.ErrorResponse
 // @Failure 404 {object} schemas.ErrorResponse
+// @Header 404 {string} X-Cache "miss, or negative if the order is known to be missing"
 // @Failure 500 {object} schemas.ErrorResponse
+// @Failure 503 {object} schemas.ErrorResponse "the read could not be audited"
 // @Router /api/v1/order/{id} [get]
 func (h *Handler) GetOrderByID(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -38,18 +44,49 @@ func (h *Handler) GetOrderByID(c *fiber.Ctx) error {
 			JSON(schemas.ErrorResponse{Error: "missing order id"})
 	}
 
-	order, err := h.service.GetOrder(c.UserContext(), id)
+	record := auditRecord(c, models.AuditActionOrderRead)
+	record.OrderUID = id
+
+	order, cacheStatus, err := h.service.GetOrder(c.UserContext(), id)
+	c.Set(schemas.CacheStatusHeader, string(cacheStatus))
 	if err != nil {
 		if errors.Is(err, errs.ErrOrderNotFound) {
+			record.Result = models.AuditResultNotFound
+			_ = h.auditor.Record(c.UserContext(), record)
 			return c.Status(http.StatusNotFound).
 				JSON(schemas.ErrorResponse{Error: err.Error()})
 		}
 
+		record.Result = models.AuditResultError
+		_ = h.auditor.Record(c.UserContext(), record)
 		return c.Status(http.StatusInternalServerError).
 			JSON(schemas.ErrorResponse{Error: errs.ErrInternalServerError.Error()})
 	}
+	record.Result = models.AuditResultOK
+	record.Fields = models.OrderFields
+
+	return sendAudited(c, h.auditor, record, order)
+}
+
+// sendAudited sends body only once the read is queued for the audit trail
+func sendAudited(c *fiber.Ctx, auditor *service.Auditor, record *models.AuditRecord, body any) error {
+	if err := auditor.Record(c.UserContext(), record); err != nil {
+		return c.Status(http.StatusServiceUnavailable).
+			JSON(schemas.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(body)
+}
 
-	return c.Status(http.StatusOK).JSON(order)
+// auditRecord starts the audit record of the request. The caller is the client IP,
+// X-Caller-ID is only kept as the claimed caller since the client can send anything in it
+func auditRecord(c *fiber.Ctx, action string) *models.AuditRecord {
+	return &models.AuditRecord{
+		Caller:        c.IP(),
+		ClaimedCaller: c.Get(schemas.CallerHeader),
+		Action:        action,
+		Route:         c.Route().Path,
+	}
 }
 
 // Ping godoc
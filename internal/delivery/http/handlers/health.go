@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jaam8/wb_tech_school_l0/pkg/health"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(r *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		registry: r,
+	}
+}
+
+// Live godoc
+// @Summary liveness probe
+// @Description Returns 200 while the process is running, dependencies are not checked
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Router /healthz/live [get]
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(health.Report{Status: health.StatusUp})
+}
+
+// Ready godoc
+// @Summary readiness probe
+// @Description Runs the registered dependency checks and returns per-check details
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /healthz/ready [get]
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	report := h.registry.Check(c.UserContext())
+	if report.Status != health.StatusUp {
+		return c.Status(http.StatusServiceUnavailable).JSON(report)
+	}
+
+	return c.Status(http.StatusOK).JSON(report)
+}
@@ -1,34 +1,88 @@
 package middlewares
 
 import (
-	"context"
+	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/schemas"
 	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
 	"go.uber.org/zap"
 )
 
-func LogMiddleware() fiber.Handler {
+const (
+	JSONFormat = "json"
+	// CLFFormat puts the request in the NCSA combined log format into the clf field,
+	// the entry goes through the logger like the JSON one
+	CLFFormat = "clf"
+
+	clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// LogMiddleware assigns a request ID and writes one access log line per request in the given format,
+// requests taking at least slowThreshold are logged with slow set, 0 disables it
+func LogMiddleware(format string, slowThreshold time.Duration) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx, err := logger.New(c.UserContext())
-		if err != nil {
-			ctx, _ = logger.New(context.Background())
+		start := time.Now()
+
+		requestID := c.Get(schemas.RequestIDHeader)
+		if requestID == "" {
+			requestID = utils.UUIDv4()
 		}
+		c.Set(schemas.RequestIDHeader, requestID)
 
+		ctx := logger.ContextWithRequestID(c.UserContext(), requestID)
 		c.SetUserContext(ctx)
 
-		logger.Info(ctx, "Request",
-			zap.String("method", c.Method()),
-			zap.String("path", c.Path()),
-		)
-		err = c.Next()
+		if err := c.Next(); err != nil {
+			if err = c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		duration := time.Since(start)
+		slow := slowThreshold > 0 && duration >= slowThreshold
+		status := c.Response().StatusCode()
+		size := len(c.Response().Body())
 
-		logger.Info(ctx, "Response",
-			zap.Int("status", c.Response().StatusCode()),
-			zap.String("method", c.Method()),
-			zap.String("path", c.Path()),
-		)
+		cacheStatus := c.GetRespHeader(schemas.CacheStatusHeader)
+
+		var fields []zap.Field
+		if format == CLFFormat {
+			fields = []zap.Field{
+				zap.String("clf", fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %d \"%s\" \"%s\"",
+					c.IP(),
+					start.Format(clfTimeLayout),
+					c.Method(), c.OriginalURL(), c.Request().Header.Protocol(),
+					status, size,
+					c.Get(fiber.HeaderReferer), c.Get(fiber.HeaderUserAgent),
+				)),
+				zap.String("route", c.Route().Path),
+				zap.Duration("duration", duration),
+			}
+		} else {
+			fields = []zap.Field{
+				zap.String("method", c.Method()),
+				zap.String("path", c.Path()),
+				zap.String("route", c.Route().Path),
+				zap.Int("status", status),
+				zap.Duration("duration", duration),
+				zap.Int("bytes", size),
+				zap.String("client_ip", c.IP()),
+				zap.String("user_agent", c.Get(fiber.HeaderUserAgent)),
+			}
+		}
+		if cacheStatus != "" {
+			fields = append(fields, zap.String("cache", cacheStatus))
+		}
+
+		if slow {
+			logger.Warn(ctx, "request", append(fields, zap.Bool("slow", true))...)
+			return nil
+		}
+		logger.Info(ctx, "request", fields...)
 
-		return err
+		return nil
 	}
 }
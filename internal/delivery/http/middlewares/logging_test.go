@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jaam8/wb_tech_school_l0/internal/delivery/http/schemas"
+	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestApp(t *testing.T, format string) (*fiber.App, *observer.ObservedLogs) {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := logger.Root()
+	logger.SetRoot(logger.FromZap(zap.New(core)))
+	t.Cleanup(func() { logger.SetRoot(prev) })
+
+	app := fiber.New()
+	app.Use(LogMiddleware(format, 10*time.Millisecond))
+	app.Get("/orders/:id", func(c *fiber.Ctx) error {
+		logger.Info(c.UserContext(), "handler")
+		c.Set(schemas.CacheStatusHeader, "hit")
+		return c.SendString("order")
+	})
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.SendStatus(http.StatusOK)
+	})
+
+	return app, logs
+}
+
+func TestLogMiddleware_JSON(t *testing.T) {
+	app, logs := newTestApp(t, JSONFormat)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/abc", nil)
+	req.Header.Set(schemas.RequestIDHeader, "req-1")
+	req.Header.Set(fiber.HeaderUserAgent, "test-agent")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, "req-1", resp.Header.Get(schemas.RequestIDHeader))
+
+	access := logs.FilterMessage("request").All()
+	require.Len(t, access, 1, "one access log line per request")
+	fields := access[0].ContextMap()
+	require.Equal(t, "/orders/:id", fields["route"])
+	require.Equal(t, int64(http.StatusOK), fields["status"])
+	require.Equal(t, int64(len("order")), fields["bytes"])
+	require.Equal(t, "test-agent", fields["user_agent"])
+	require.Equal(t, "hit", fields["cache"])
+	require.Equal(t, "req-1", fields["request_id"])
+
+	handlerLine := logs.FilterMessage("handler").All()
+	require.Len(t, handlerLine, 1)
+	require.Equal(t, "req-1", handlerLine[0].ContextMap()["request_id"],
+		"logs written by handlers should carry the request ID")
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Header.Get(schemas.RequestIDHeader), "request ID should be generated")
+
+	access = logs.FilterMessage("request").All()
+	require.Len(t, access, 2)
+	require.Equal(t, zapcore.WarnLevel, access[1].Level)
+	require.Equal(t, true, access[1].ContextMap()["slow"])
+}
+
+func TestLogMiddleware_CLF(t *testing.T) {
+	app, logs := newTestApp(t, CLFFormat)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/abc?x=1", nil)
+	req.Header.Set(fiber.HeaderUserAgent, "test-agent")
+	req.Header.Set(schemas.RequestIDHeader, "req-1")
+	_, err := app.Test(req)
+	require.NoError(t, err)
+
+	access := logs.FilterMessage("request").All()
+	require.Len(t, access, 1, "one access log line per request")
+	fields := access[0].ContextMap()
+	require.Regexp(t,
+		`^0\.0\.0\.0 - - \[[^\]]+\] "GET /orders/abc\?x=1 HTTP/1\.1" 200 5 "" "test-agent"$`,
+		fields["clf"])
+	require.Equal(t, "/orders/:id", fields["route"])
+	require.Equal(t, "hit", fields["cache"])
+	require.Equal(t, "req-1", fields["request_id"])
+	require.NotContains(t, fields, "method", "the request is only in the clf field")
+
+	_, err = app.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	require.NoError(t, err)
+	access = logs.FilterMessage("request").All()
+	require.Len(t, access, 2)
+	require.Equal(t, zapcore.WarnLevel, access[1].Level)
+	require.Equal(t, true, access[1].ContextMap()["slow"])
+	require.NotContains(t, access[1].ContextMap(), "cache")
+}
@@ -0,0 +1,11 @@
+package schemas
+
+const (
+	// CallerHeader is the identity the caller claims, it is kept in the audit trail next to the client IP
+	// and is not verified
+	CallerHeader = "X-Caller-ID"
+	// CacheStatusHeader tells the client and the access log whether the order was served from cache
+	CacheStatusHeader = "X-Cache"
+	// RequestIDHeader is taken from the request or generated, and echoed in the response
+	RequestIDHeader = "X-Request-ID"
+)
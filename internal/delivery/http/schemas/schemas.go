@@ -4,3 +4,27 @@ type ErrorResponse struct {
 	Success bool   `example:"false"         json:"success"`
 	Error   string `example:"error message" json:"error"`
 }
+
+type LogLevelRequest struct {
+	Level     string `example:"debug"    json:"level"`
+	Component string `example:"consumer" json:"component,omitempty"`
+}
+
+type LogLevelResponse struct {
+	Level      string            `example:"info" json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+type CacheKeysResponse struct {
+	Keys  []string `example:"b563feb7b2b84b6test" json:"keys"`
+	Count int      `example:"1"                   json:"count"`
+}
+
+type BuildInfoResponse struct {
+	GoVersion string `example:"go1.25.0"                                 json:"go_version"`
+	Path      string `example:"github.com/jaam8/wb_tech_school_l0/cmd/app" json:"path"`
+	Version   string `example:"(devel)"                                  json:"version"`
+	Revision  string `example:"6c4466e"                                  json:"revision,omitempty"`
+	Time      string `example:"2025-10-01T12:00:00Z"                     json:"time,omitempty"`
+	Modified  bool   `example:"false"                                    json:"modified"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type CacheStats struct {
+	Len         int         `json:"len"`
+	Capacity    int         `json:"capacity"`
+	Cost        int64       `json:"cost"`     // estimated bytes held by cached orders
+	MaxCost     int64       `json:"max_cost"` // zero if the cache is not bounded by size
+	Hits        uint64      `json:"hits"`
+	StaleHits   uint64      `json:"stale_hits"`
+	Misses      uint64      `json:"misses"`
+	Evictions   uint64      `json:"evictions"`
+	Expirations uint64      `json:"expirations"`
+	Remote      *CacheStats `json:"remote,omitempty"` // shared tier of the tiered cache, counted by this instance
+}
+
+type CacheEntry struct {
+	Key       string    `json:"key"`
+	Value     *Order    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"` // zero if the entry never expires
+	Expired   bool      `json:"expired"`
+}
+
+type ConsumerStats struct {
+	Topic     string `json:"topic"`
+	Partition string `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Lag       int64  `json:"lag"`
+	Messages  int64  `json:"messages"`
+	Errors    int64  `json:"errors"`
+}
+
+type ConsumerState struct {
+	Broker        ConsumerStats `json:"broker"`
+	InFlightBatch int           `json:"in_flight_batch"`
+	LastHeartbeat time.Time     `json:"last_heartbeat"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	AuditActionOrderRead      = "order_read"
+	AuditActionLogLevelChange = "log_level_change"
+	AuditActionCacheDelete    = "cache_delete"
+	AuditActionCachePurge     = "cache_purge"
+
+	AuditResultOK       = "ok"
+	AuditResultNotFound = "not_found"
+	AuditResultError    = "error"
+)
+
+// AuditRecord is an entry of the audit trail. Caller is the client IP,
+// ClaimedCaller is the identity the client sent in X-Caller-ID, it is not verified
+type AuditRecord struct {
+	ID            int64     `db:"id"             json:"id"`
+	OccurredAt    time.Time `db:"occurred_at"    json:"occurred_at"`
+	Caller        string    `db:"caller"         json:"caller"`
+	ClaimedCaller string    `db:"claimed_caller" json:"claimed_caller,omitempty"`
+	Action        string    `db:"action"         json:"action"`
+	OrderUID      string    `db:"order_uid"      json:"order_uid,omitempty"`
+	Route         string    `db:"route"          json:"route"`
+	Result        string    `db:"result"         json:"result"`
+	Fields        []string  `db:"fields"         json:"fields,omitempty"`
+	Details       string    `db:"details"        json:"details,omitempty"`
+}
+
+type AuditFilter struct {
+	OrderUID      string
+	Caller        string
+	ClaimedCaller string
+	From          time.Time
+	To            time.Time
+	Limit         int
+}
+
+// OrderFields lists the JSON paths revealed when a whole order is returned
+var OrderFields = jsonFields(reflect.TypeFor[Order](), "")
+
+func jsonFields(t reflect.Type, prefix string) []string {
+	var fields []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		path := prefix + name
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(ft.Elem(), path+"[].")...)
+			continue
+		}
+		if ft.Kind() == reflect.Struct && ft != reflect.TypeFor[time.Time]() {
+			fields = append(fields, jsonFields(ft, path+".")...)
+			continue
+		}
+		fields = append(fields, path)
+	}
+
+	return fields
+}
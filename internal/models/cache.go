@@ -0,0 +1,17 @@
+package models
+
+// CacheStatus tells where an order returned to the client came from
+type CacheStatus string
+
+const (
+	CacheHit  CacheStatus = "hit"
+	CacheMiss CacheStatus = "miss"
+	// CacheStale is reported for an expired order served from cache while it is being reloaded
+	CacheStale CacheStatus = "stale"
+	// CacheNegative is reported when an order is known to be missing
+	// without asking the storage, the response is then 404
+	CacheNegative CacheStatus = "negative"
+	// CacheRemote is reported for an order missing from the local cache
+	// but found in the shared remote one, it is then copied to the local cache
+	CacheRemote CacheStatus = "remote"
+)
@@ -3,6 +3,7 @@ package broker
 import (
 	"context"
 	"encoding/json"
+	"sync/atomic"
 
 	"github.com/jaam8/wb_tech_school_l0/internal/models"
 	"github.com/segmentio/kafka-go"
@@ -10,13 +11,16 @@ import (
 
 type KafkaConsumerAdapter struct {
 	consumer *kafka.Reader
+	// the reader resets its counters on every Stats call, the totals are kept here
+	messages atomic.Int64
+	errors   atomic.Int64
 }
 
 func NewKafkaConsumerAdapter(consumer *kafka.Reader) *KafkaConsumerAdapter {
 	return &KafkaConsumerAdapter{consumer: consumer}
 }
 
-func (a KafkaConsumerAdapter) ConsumeOrderEvent(ctx context.Context) (*models.Order, error) {
+func (a *KafkaConsumerAdapter) ConsumeOrderEvent(ctx context.Context) (*models.Order, error) {
 	var order models.Order
 	msg, err := a.consumer.ReadMessage(ctx)
 	if err != nil {
@@ -29,3 +33,20 @@ func (a KafkaConsumerAdapter) ConsumeOrderEvent(ctx context.Context) (*models.Or
 
 	return &order, nil
 }
+
+// Stats returns the current offset and lag of the reader
+// and the messages and errors counted since the adapter was created
+func (a *KafkaConsumerAdapter) Stats() models.ConsumerStats {
+	stats := a.consumer.Stats()
+	messages := a.messages.Add(stats.Messages)
+	errs := a.errors.Add(stats.Errors)
+
+	return models.ConsumerStats{
+		Topic:     stats.Topic,
+		Partition: stats.Partition,
+		Offset:    stats.Offset,
+		Lag:       stats.Lag,
+		Messages:  messages,
+		Errors:    errs,
+	}
+}
@@ -0,0 +1,78 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// invalidation is the value of a message of the invalidation topic, keyed by the order id
+// so that compaction keeps only the last invalidation of every order
+type invalidation struct {
+	OrderUID      string    `json:"order_uid"`
+	InvalidatedAt time.Time `json:"invalidated_at"`
+}
+
+// KafkaInvalidator broadcasts invalidations over a compacted topic. The reader is expected
+// to be in a consumer group of its own, see kafka.NewBroadcastReader, so that every instance
+// receives every invalidation, including its own
+type KafkaInvalidator struct {
+	producer *kafka.Writer
+	consumer *kafka.Reader
+}
+
+func NewKafkaInvalidator(producer *kafka.Writer, consumer *kafka.Reader) *KafkaInvalidator {
+	return &KafkaInvalidator{
+		producer: producer,
+		consumer: consumer,
+	}
+}
+
+func (i *KafkaInvalidator) PublishInvalidation(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	msgs := make([]kafka.Message, 0, len(ids))
+	for _, id := range ids {
+		value, err := json.Marshal(invalidation{OrderUID: id, InvalidatedAt: now})
+		if err != nil {
+			return fmt.Errorf("failed to encode invalidation: %w", err)
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:   []byte(id),
+			Value: value,
+		})
+	}
+
+	if err := i.producer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to publish invalidation: %w", err)
+	}
+
+	return nil
+}
+
+func (i *KafkaInvalidator) SubscribeInvalidations(ctx context.Context, fn func(ids []string)) error {
+	for {
+		msg, err := i.consumer.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read invalidation: %w", err)
+		}
+
+		var inv invalidation
+		if err = json.Unmarshal(msg.Value, &inv); err != nil || inv.OrderUID == "" {
+			// compaction tombstones and foreign messages carry no order id
+			continue
+		}
+		fn([]string{inv.OrderUID})
+	}
+}
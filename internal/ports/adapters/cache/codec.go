@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	CodecJSON    = "json"
+	CodecMsgpack = "msgpack"
+)
+
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Codec serializes the orders stored by a remote cache
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// NewCodec returns the codec with the given name, CodecJSON or CodecMsgpack
+func NewCodec(name string) (Codec, error) {
+	switch name {
+	case CodecJSON:
+		return jsonCodec{}, nil
+	case CodecMsgpack:
+		return msgpackCodec{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCodec, name)
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec reuses the json tags of the models, so both codecs name the fields alike
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// invalidation is the message sent over the invalidation channel
+type invalidation struct {
+	Source string   `json:"source"`
+	IDs    []string `json:"ids"`
+}
+
+// RedisInvalidator broadcasts invalidations over Redis pub/sub. Messages are not persisted,
+// an instance disconnected from Redis misses them and relies on the TTL of its local cache
+type RedisInvalidator struct {
+	client  goredis.UniversalClient
+	channel string
+	// source tells the messages of this instance apart, so it does not drop what it has just saved
+	source string
+}
+
+func NewRedisInvalidator(client goredis.UniversalClient, channel string) *RedisInvalidator {
+	return &RedisInvalidator{
+		client:  client,
+		channel: channel,
+		source:  uuid.NewString(),
+	}
+}
+
+func (i *RedisInvalidator) PublishInvalidation(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(invalidation{Source: i.source, IDs: ids})
+	if err != nil {
+		return fmt.Errorf("failed to encode invalidation: %w", err)
+	}
+	if err = i.client.Publish(ctx, i.channel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish invalidation: %w", err)
+	}
+
+	return nil
+}
+
+func (i *RedisInvalidator) SubscribeInvalidations(ctx context.Context, fn func(ids []string)) error {
+	pubsub := i.client.Subscribe(ctx, i.channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to invalidations: %w", err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var inv invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil || inv.Source == i.source {
+				continue
+			}
+			fn(inv.IDs)
+		}
+	}
+}
@@ -1,25 +1,81 @@
 package cache
 
 import (
+	"context"
 	"errors"
+	"time"
+	"unsafe"
 
 	"github.com/jaam8/wb_tech_school_l0/internal/models"
 	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
 	lrucache "github.com/jaam8/wb_tech_school_l0/pkg/lru-cache"
 )
 
+// OrderTTL picks the cache TTL of an order
+type OrderTTL func(order *models.Order) time.Duration
+
+// AgeBasedTTL keeps orders created within the recent window for hot, and older ones for cold,
+// so recent orders stay cached while old, rarely read ones expire sooner
+func AgeBasedTTL(hot, cold, recent time.Duration) OrderTTL {
+	return func(order *models.Order) time.Duration {
+		if order == nil || time.Since(order.DateCreated) > recent {
+			return cold
+		}
+		return hot
+	}
+}
+
+// FixedTTL keeps every order for ttl
+func FixedTTL(ttl time.Duration) OrderTTL {
+	return func(*models.Order) time.Duration {
+		return ttl
+	}
+}
+
+// OrderCost estimates the memory held by an order in bytes: the structs themselves
+// plus the contents of their strings. It is meant for lrucache.SetMaxCost
+func OrderCost(order *models.Order) int64 {
+	if order == nil {
+		return 0
+	}
+
+	size := int(unsafe.Sizeof(*order)) + cap(order.Items)*int(unsafe.Sizeof(models.Item{})) +
+		len(order.OrderUID) + len(order.TrackNumber) + len(order.Entry) + len(order.Locale) +
+		len(order.InternalSignature) + len(order.CustomerID) + len(order.DeliveryService) +
+		len(order.Shardkey) + len(order.OofShard)
+
+	d := order.Delivery
+	size += len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) + len(d.Address) + len(d.Region) + len(d.Email)
+
+	p := order.Payment
+	size += len(p.Transaction) + len(p.RequestID) + len(p.Provider) + len(p.Currency) + len(p.Bank)
+
+	for _, item := range order.Items {
+		size += len(item.TrackNumber) + len(item.Rid) + len(item.Name) + len(item.Size) + len(item.Brand)
+	}
+
+	return int64(size)
+}
+
 type InMemoryCacheAdapter struct {
-	client *lrucache.InMemoryCache
+	client lrucache.Cache[string, *models.Order]
+	ttl    OrderTTL
+
+	// notFound caches the ids the loader reported as missing, nil if disabled
+	notFound    lrucache.Cache[string, struct{}]
+	notFoundTTL time.Duration
 }
 
-func NewInMemoryCacheAdapter(client *lrucache.InMemoryCache) *InMemoryCacheAdapter {
+// NewInMemoryCacheAdapter creates the adapter, a nil ttl keeps the default TTL of the client
+func NewInMemoryCacheAdapter(client lrucache.Cache[string, *models.Order], ttl OrderTTL) *InMemoryCacheAdapter {
 	return &InMemoryCacheAdapter{
 		client: client,
+		ttl:    ttl,
 	}
 }
 
 func (a *InMemoryCacheAdapter) GetOrder(key string) (*models.Order, error) {
-	val, err := a.client.Get(key)
+	order, err := a.client.Get(key)
 	if err != nil {
 		if errors.Is(err, lrucache.ErrNotFound) {
 			return nil, errs.ErrOrderNotFound
@@ -27,14 +83,141 @@ func (a *InMemoryCacheAdapter) GetOrder(key string) (*models.Order, error) {
 		return nil, err
 	}
 
-	order, ok := val.(*models.Order)
-	if !ok {
-		return nil, errs.ErrOrderNotFound
+	return order, nil
+}
+
+// WithNotFoundCache makes GetOrLoadOrder remember for ttl the ids its loader returned
+// errs.ErrOrderNotFound for, so repeated requests for unknown ids do not reach the storage.
+// It must be called before the adapter is used
+func (a *InMemoryCacheAdapter) WithNotFoundCache(
+	notFound lrucache.Cache[string, struct{}], ttl time.Duration,
+) *InMemoryCacheAdapter {
+	a.notFound = notFound
+	a.notFoundTTL = ttl
+	return a
+}
+
+func (a *InMemoryCacheAdapter) GetOrLoadOrder(
+	ctx context.Context, id string, loader func(ctx context.Context) (*models.Order, error),
+) (*models.Order, models.CacheStatus, error) {
+	if a.notFound != nil {
+		if _, err := a.notFound.Get(id); err == nil {
+			return nil, models.CacheNegative, errs.ErrOrderNotFound
+		}
+	}
+
+	order, loadStatus, err := a.client.GetOrLoad(ctx, id, func(ctx context.Context) (*models.Order, time.Duration, error) {
+		order, err := loader(ctx)
+		if err != nil {
+			if a.notFound != nil && errors.Is(err, errs.ErrOrderNotFound) {
+				_ = a.notFound.SetWithTTL(id, struct{}{}, a.notFoundTTL)
+			}
+			return nil, 0, err
+		}
+		if a.ttl == nil {
+			return order, 0, nil
+		}
+		return order, a.ttl(order), nil
+	})
+
+	status := models.CacheMiss
+	switch loadStatus {
+	case lrucache.LoadHit:
+		status = models.CacheHit
+	case lrucache.LoadStale:
+		status = models.CacheStale
 	}
 
-	return order, nil
+	return order, status, err
 }
 
 func (a *InMemoryCacheAdapter) SaveOrder(key string, val *models.Order) error {
-	return a.client.Set(key, val)
+	if a.ttl == nil {
+		return a.client.Set(key, val)
+	}
+	return a.client.SetWithTTL(key, val, a.ttl(val))
+}
+
+func (a *InMemoryCacheAdapter) HasOrder(key string) bool {
+	_, _, err := a.client.Peek(key)
+	return err == nil
+}
+
+func (a *InMemoryCacheAdapter) RefreshOrder(key string, val *models.Order) error {
+	a.InvalidateNotFound(key)
+	return a.SaveOrder(key, val)
+}
+
+// InvalidateNotFound drops the not found results of the ids. A load that started before
+// the ids were stored may still cache a not found result, it is bounded by the not found TTL
+func (a *InMemoryCacheAdapter) InvalidateNotFound(ids ...string) {
+	if a.notFound == nil {
+		return
+	}
+	for _, id := range ids {
+		_ = a.notFound.Delete(id)
+	}
+}
+
+func (a *InMemoryCacheAdapter) InvalidateOrders(ids ...string) {
+	for _, id := range ids {
+		_ = a.client.Delete(id)
+	}
+	a.InvalidateNotFound(ids...)
+}
+
+func (a *InMemoryCacheAdapter) PeekOrder(key string) (*models.CacheEntry, error) {
+	order, expiredAt, err := a.client.Peek(key)
+	if err != nil {
+		if errors.Is(err, lrucache.ErrNotFound) {
+			return nil, errs.ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	return &models.CacheEntry{
+		Key:       key,
+		Value:     order,
+		ExpiresAt: expiredAt,
+		Expired:   !expiredAt.IsZero() && time.Now().After(expiredAt),
+	}, nil
+}
+
+func (a *InMemoryCacheAdapter) Keys(limit int) []string {
+	keys := a.client.Keys()
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+func (a *InMemoryCacheAdapter) DeleteOrder(key string) error {
+	err := a.client.Delete(key)
+	if errors.Is(err, lrucache.ErrNotFound) {
+		return errs.ErrOrderNotFound
+	}
+	return err
+}
+
+func (a *InMemoryCacheAdapter) Purge() {
+	a.client.Purge()
+	if a.notFound != nil {
+		a.notFound.Purge()
+	}
+}
+
+func (a *InMemoryCacheAdapter) Stats() models.CacheStats {
+	stats := a.client.Stats()
+
+	return models.CacheStats{
+		Len:         stats.Len,
+		Capacity:    stats.Capacity,
+		Cost:        stats.Cost,
+		MaxCost:     stats.MaxCost,
+		Hits:        stats.Hits,
+		StaleHits:   stats.StaleHits,
+		Misses:      stats.Misses,
+		Evictions:   stats.Evictions,
+		Expirations: stats.Expirations,
+	}
 }
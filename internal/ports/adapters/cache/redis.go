@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	redisOrderKey    = "order:"
+	redisNotFoundKey = "notfound:"
+	redisScanCount   = 1000
+)
+
+// RedisCacheAdapter keeps the orders in Redis, so that they are shared by all instances
+// of the service and survive restarts. Hits and misses are counted per instance
+type RedisCacheAdapter struct {
+	client goredis.UniversalClient
+	codec  Codec
+	prefix string
+	ttl    OrderTTL
+
+	// notFoundTTL is how long not found results are cached, zero if disabled
+	notFoundTTL time.Duration
+
+	loads       singleflight.Group
+	loadTimeout time.Duration
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewRedisCacheAdapter creates the adapter, every key it uses starts with prefix.
+// A nil ttl stores the orders without expiration
+func NewRedisCacheAdapter(client goredis.UniversalClient, codec Codec, prefix string, ttl OrderTTL) *RedisCacheAdapter {
+	return &RedisCacheAdapter{
+		client: client,
+		codec:  codec,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// WithNotFoundTTL makes GetOrLoadOrder remember for ttl the ids its loader returned
+// errs.ErrOrderNotFound for. It must be called before the adapter is used
+func (a *RedisCacheAdapter) WithNotFoundTTL(ttl time.Duration) *RedisCacheAdapter {
+	a.notFoundTTL = ttl
+	return a
+}
+
+// WithLoadTimeout bounds the loads of GetOrLoadOrder, which outlive the request that started them.
+// It must be called before the adapter is used
+func (a *RedisCacheAdapter) WithLoadTimeout(timeout time.Duration) *RedisCacheAdapter {
+	a.loadTimeout = timeout
+	return a
+}
+
+func (a *RedisCacheAdapter) orderKey(id string) string {
+	return a.prefix + redisOrderKey + id
+}
+
+func (a *RedisCacheAdapter) notFoundKey(id string) string {
+	return a.prefix + redisNotFoundKey + id
+}
+
+func (a *RedisCacheAdapter) orderTTL(order *models.Order) time.Duration {
+	if a.ttl == nil {
+		return 0
+	}
+	return a.ttl(order)
+}
+
+func (a *RedisCacheAdapter) GetOrder(key string) (*models.Order, error) {
+	return a.getOrder(context.Background(), key)
+}
+
+func (a *RedisCacheAdapter) getOrder(ctx context.Context, key string) (*models.Order, error) {
+	data, err := a.client.Get(ctx, a.orderKey(key)).Bytes()
+	if err != nil {
+		a.misses.Add(1)
+		if errors.Is(err, goredis.Nil) {
+			return nil, errs.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to get order from redis: %w", err)
+	}
+
+	var order models.Order
+	if err = a.codec.Unmarshal(data, &order); err != nil {
+		a.misses.Add(1)
+		return nil, fmt.Errorf("failed to decode cached order: %w", err)
+	}
+	a.hits.Add(1)
+
+	return &order, nil
+}
+
+// GetOrLoadOrder falls back to loader when Redis is unavailable, so an outage
+// of the cache slows requests down instead of failing them
+func (a *RedisCacheAdapter) GetOrLoadOrder(
+	ctx context.Context, id string, loader func(ctx context.Context) (*models.Order, error),
+) (*models.Order, models.CacheStatus, error) {
+	if order, err := a.getOrder(ctx, id); err == nil {
+		return order, models.CacheHit, nil
+	}
+
+	if a.notFoundTTL > 0 {
+		if n, err := a.client.Exists(ctx, a.notFoundKey(id)).Result(); err == nil && n > 0 {
+			return nil, models.CacheNegative, errs.ErrOrderNotFound
+		}
+	}
+
+	// concurrent misses of this instance share a single load
+	result, err, _ := a.loads.Do(id, func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		if a.loadTimeout > 0 {
+			var cancel context.CancelFunc
+			loadCtx, cancel = context.WithTimeout(loadCtx, a.loadTimeout)
+			defer cancel()
+		}
+
+		order, err := loader(loadCtx)
+		if err != nil {
+			if a.notFoundTTL > 0 && errors.Is(err, errs.ErrOrderNotFound) {
+				_ = a.client.Set(loadCtx, a.notFoundKey(id), 1, a.notFoundTTL).Err()
+			}
+			return nil, err
+		}
+		_ = a.saveOrder(loadCtx, id, order)
+
+		return order, nil
+	})
+	if err != nil {
+		return nil, models.CacheMiss, err
+	}
+
+	return result.(*models.Order), models.CacheMiss, nil
+}
+
+func (a *RedisCacheAdapter) SaveOrder(key string, val *models.Order) error {
+	return a.saveOrder(context.Background(), key, val)
+}
+
+func (a *RedisCacheAdapter) saveOrder(ctx context.Context, key string, val *models.Order) error {
+	if val == nil {
+		return fmt.Errorf("cannot set nil value to key %v", key)
+	}
+
+	data, err := a.codec.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("failed to encode order: %w", err)
+	}
+	if err = a.client.Set(ctx, a.orderKey(key), data, a.orderTTL(val)).Err(); err != nil {
+		return fmt.Errorf("failed to save order to redis: %w", err)
+	}
+
+	return nil
+}
+
+// HasOrder reports false when Redis is unavailable
+func (a *RedisCacheAdapter) HasOrder(key string) bool {
+	n, err := a.client.Exists(context.Background(), a.orderKey(key)).Result()
+	return err == nil && n > 0
+}
+
+func (a *RedisCacheAdapter) RefreshOrder(key string, val *models.Order) error {
+	a.InvalidateNotFound(key)
+	return a.SaveOrder(key, val)
+}
+
+func (a *RedisCacheAdapter) InvalidateNotFound(ids ...string) {
+	if a.notFoundTTL <= 0 || len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = a.notFoundKey(id)
+	}
+	_ = a.client.Del(context.Background(), keys...).Err()
+}
+
+func (a *RedisCacheAdapter) InvalidateOrders(ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, 2*len(ids))
+	for _, id := range ids {
+		keys = append(keys, a.orderKey(id), a.notFoundKey(id))
+	}
+	_ = a.client.Del(context.Background(), keys...).Err()
+}
+
+func (a *RedisCacheAdapter) PeekOrder(key string) (*models.CacheEntry, error) {
+	ctx := context.Background()
+
+	var get *goredis.StringCmd
+	var ttl *goredis.DurationCmd
+	_, err := a.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		get = pipe.Get(ctx, a.orderKey(key))
+		ttl = pipe.PTTL(ctx, a.orderKey(key))
+		return nil
+	})
+	if errors.Is(err, goredis.Nil) {
+		return nil, errs.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek order in redis: %w", err)
+	}
+
+	data, _ := get.Bytes()
+	var order models.Order
+	if err = a.codec.Unmarshal(data, &order); err != nil {
+		return nil, fmt.Errorf("failed to decode cached order: %w", err)
+	}
+
+	// Redis drops expired keys itself, a negative TTL means the key has no expiration
+	var expiresAt time.Time
+	if remaining := ttl.Val(); remaining > 0 {
+		expiresAt = time.Now().Add(remaining)
+	}
+
+	return &models.CacheEntry{
+		Key:       key,
+		Value:     &order,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Keys walks the keyspace with SCAN until it has found limit orders
+func (a *RedisCacheAdapter) Keys(limit int) []string {
+	ids := make([]string, 0)
+	prefix := a.prefix + redisOrderKey
+	errLimit := errors.New("limit reached")
+	_ = a.scan(context.Background(), prefix+"*", func(keys []string) error {
+		for _, key := range keys {
+			ids = append(ids, strings.TrimPrefix(key, prefix))
+			if limit > 0 && len(ids) >= limit {
+				return errLimit
+			}
+		}
+		return nil
+	})
+
+	return ids
+}
+
+func (a *RedisCacheAdapter) DeleteOrder(key string) error {
+	n, err := a.client.Del(context.Background(), a.orderKey(key)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete order from redis: %w", err)
+	}
+	if n == 0 {
+		return errs.ErrOrderNotFound
+	}
+	return nil
+}
+
+// Purge removes every key under the prefix of the adapter, for all instances sharing it
+func (a *RedisCacheAdapter) Purge() {
+	ctx := context.Background()
+	_ = a.scan(ctx, a.prefix+"*", func(keys []string) error {
+		return a.client.Unlink(ctx, keys...).Err()
+	})
+}
+
+// Stats counts the cached orders with SCAN, which walks the whole keyspace of the database
+func (a *RedisCacheAdapter) Stats() models.CacheStats {
+	length := 0
+	_ = a.scan(context.Background(), a.prefix+redisOrderKey+"*", func(keys []string) error {
+		length += len(keys)
+		return nil
+	})
+
+	return models.CacheStats{
+		Len:    length,
+		Hits:   a.hits.Load(),
+		Misses: a.misses.Load(),
+	}
+}
+
+// Ping checks that Redis is reachable, it is meant for the health registry
+func (a *RedisCacheAdapter) Ping(ctx context.Context) error {
+	return a.client.Ping(ctx).Err()
+}
+
+func (a *RedisCacheAdapter) scan(ctx context.Context, match string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := a.client.Scan(ctx, cursor, match, redisScanCount).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan redis keys: %w", err)
+		}
+		if len(keys) > 0 {
+			if err = fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
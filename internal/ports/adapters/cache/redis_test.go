@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *goredis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return server, client
+}
+
+func newTestOrder(id string) *models.Order {
+	return &models.Order{
+		OrderUID:    id,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery:    models.Delivery{Name: "Test Testov", City: "Kiryat Mozkin"},
+		Payment:     models.Payment{Transaction: id, Currency: "USD", Amount: 1817},
+		Items: []models.Item{
+			{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Name: "Mascaras"},
+		},
+		Locale:      "en",
+		CustomerID:  "test",
+		SmID:        99,
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:    "1",
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	for _, name := range []string{CodecJSON, CodecMsgpack} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := NewCodec(name)
+			require.NoError(t, err)
+
+			want := newTestOrder("b563feb7b2b84b6test")
+			data, err := codec.Marshal(want)
+			require.NoError(t, err)
+
+			var got models.Order
+			require.NoError(t, codec.Unmarshal(data, &got))
+			assert.True(t, want.DateCreated.Equal(got.DateCreated))
+			got.DateCreated = want.DateCreated
+			assert.Equal(t, want, &got)
+		})
+	}
+
+	_, err := NewCodec("xml")
+	assert.ErrorIs(t, err, ErrUnknownCodec)
+}
+
+func TestRedisCacheAdapter(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec string
+	}{
+		{name: "json", codec: CodecJSON},
+		{name: "msgpack", codec: CodecMsgpack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newTestRedis(t)
+			codec, err := NewCodec(tt.codec)
+			require.NoError(t, err)
+			adapter := NewRedisCacheAdapter(client, codec, "test:", FixedTTL(time.Minute))
+
+			_, err = adapter.GetOrder("missing")
+			assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+
+			order := newTestOrder("first")
+			require.NoError(t, adapter.SaveOrder(order.OrderUID, order))
+			assert.True(t, server.Exists("test:order:first"))
+			assert.Equal(t, time.Minute, server.TTL("test:order:first"))
+
+			got, err := adapter.GetOrder("first")
+			require.NoError(t, err)
+			assert.Equal(t, order.OrderUID, got.OrderUID)
+			assert.Equal(t, order.Items, got.Items)
+
+			stats := adapter.Stats()
+			assert.Equal(t, 1, stats.Len)
+			assert.Equal(t, uint64(1), stats.Hits)
+			assert.Equal(t, uint64(1), stats.Misses)
+
+			server.FastForward(time.Minute)
+			_, err = adapter.GetOrder("first")
+			assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+		})
+	}
+}
+
+func TestRedisCacheAdapterNoTTL(t *testing.T) {
+	server, client := newTestRedis(t)
+	adapter := NewRedisCacheAdapter(client, jsonCodec{}, "", nil)
+
+	require.NoError(t, adapter.SaveOrder("first", newTestOrder("first")))
+	assert.Equal(t, time.Duration(0), server.TTL("order:first"))
+
+	entry, err := adapter.PeekOrder("first")
+	require.NoError(t, err)
+	assert.True(t, entry.ExpiresAt.IsZero())
+	assert.False(t, entry.Expired)
+
+	assert.Error(t, adapter.SaveOrder("nil", nil))
+}
+
+func TestRedisCacheAdapterGetOrLoadOrder(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	adapter := NewRedisCacheAdapter(client, jsonCodec{}, "test:", FixedTTL(time.Minute)).
+		WithNotFoundTTL(30 * time.Second)
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context) (*models.Order, error) {
+		loads.Add(1)
+		return newTestOrder("first"), nil
+	}
+
+	order, status, err := adapter.GetOrLoadOrder(ctx, "first", loader)
+	require.NoError(t, err)
+	assert.Equal(t, models.CacheMiss, status)
+	assert.Equal(t, "first", order.OrderUID)
+
+	order, status, err = adapter.GetOrLoadOrder(ctx, "first", loader)
+	require.NoError(t, err)
+	assert.Equal(t, models.CacheHit, status)
+	assert.Equal(t, "first", order.OrderUID)
+	assert.Equal(t, int32(1), loads.Load())
+
+	notFound := func(ctx context.Context) (*models.Order, error) {
+		loads.Add(1)
+		return nil, errs.ErrOrderNotFound
+	}
+	_, status, err = adapter.GetOrLoadOrder(ctx, "missing", notFound)
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+	assert.Equal(t, models.CacheMiss, status)
+	assert.Equal(t, 30*time.Second, server.TTL("test:notfound:missing"))
+
+	_, status, err = adapter.GetOrLoadOrder(ctx, "missing", notFound)
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+	assert.Equal(t, models.CacheNegative, status)
+	assert.Equal(t, int32(2), loads.Load())
+
+	adapter.InvalidateNotFound("missing")
+	assert.False(t, server.Exists("test:notfound:missing"))
+
+	failing := errors.New("storage is down")
+	_, status, err = adapter.GetOrLoadOrder(ctx, "other", func(ctx context.Context) (*models.Order, error) {
+		return nil, failing
+	})
+	assert.ErrorIs(t, err, failing)
+	assert.Equal(t, models.CacheMiss, status)
+	assert.False(t, server.Exists("test:notfound:other"))
+}
+
+func TestRedisCacheAdapterConcurrentMisses(t *testing.T) {
+	_, client := newTestRedis(t)
+	adapter := NewRedisCacheAdapter(client, jsonCodec{}, "test:", FixedTTL(time.Minute))
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (*models.Order, error) {
+		loads.Add(1)
+		<-release
+		return newTestOrder("first"), nil
+	}
+
+	const callers = 10
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for range callers {
+		go func() {
+			defer done.Done()
+			started.Done()
+			order, _, err := adapter.GetOrLoadOrder(context.Background(), "first", loader)
+			assert.NoError(t, err)
+			assert.Equal(t, "first", order.OrderUID)
+		}()
+	}
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestRedisCacheAdapterUnavailable(t *testing.T) {
+	server, client := newTestRedis(t)
+	adapter := NewRedisCacheAdapter(client, jsonCodec{}, "test:", FixedTTL(time.Minute))
+	server.Close()
+
+	assert.Error(t, adapter.Ping(context.Background()))
+
+	order, status, err := adapter.GetOrLoadOrder(context.Background(), "first",
+		func(ctx context.Context) (*models.Order, error) {
+			return newTestOrder("first"), nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, models.CacheMiss, status)
+	assert.Equal(t, "first", order.OrderUID)
+}
+
+func TestRedisCacheAdapterAdmin(t *testing.T) {
+	server, client := newTestRedis(t)
+	adapter := NewRedisCacheAdapter(client, jsonCodec{}, "test:", FixedTTL(time.Minute)).
+		WithNotFoundTTL(time.Minute)
+	require.NoError(t, server.Set("other:order:first", "kept"))
+
+	for _, id := range []string{"first", "second"} {
+		require.NoError(t, adapter.SaveOrder(id, newTestOrder(id)))
+	}
+
+	entry, err := adapter.PeekOrder("first")
+	require.NoError(t, err)
+	assert.Equal(t, "first", entry.Key)
+	assert.Equal(t, "first", entry.Value.OrderUID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), entry.ExpiresAt, time.Second)
+	assert.Equal(t, uint64(0), adapter.Stats().Hits)
+
+	_, err = adapter.PeekOrder("missing")
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+
+	assert.ElementsMatch(t, []string{"first", "second"}, adapter.Keys(0))
+	assert.Len(t, adapter.Keys(1), 1)
+
+	require.NoError(t, adapter.DeleteOrder("first"))
+	assert.ErrorIs(t, adapter.DeleteOrder("first"), errs.ErrOrderNotFound)
+	assert.Equal(t, 1, adapter.Stats().Len)
+
+	_, _, err = adapter.GetOrLoadOrder(context.Background(), "missing",
+		func(ctx context.Context) (*models.Order, error) {
+			return nil, errs.ErrOrderNotFound
+		})
+	require.ErrorIs(t, err, errs.ErrOrderNotFound)
+
+	adapter.InvalidateOrders("second", "missing")
+	assert.False(t, server.Exists("test:order:second"))
+	assert.False(t, server.Exists("test:notfound:missing"))
+
+	require.NoError(t, adapter.SaveOrder("second", newTestOrder("second")))
+	require.NoError(t, server.Set("test:notfound:missing", "1"))
+	adapter.Purge()
+	assert.Equal(t, 0, adapter.Stats().Len)
+	assert.False(t, server.Exists("test:notfound:missing"))
+	assert.True(t, server.Exists("other:order:first"))
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jaam8/wb_tech_school_l0/internal/ports"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+)
+
+// Tier is a level of the TieredCacheAdapter
+type Tier interface {
+	ports.CacheAdapter
+	ports.CacheAdminAdapter
+}
+
+// TieredCacheAdapter looks orders up in a local cache, then in a remote one shared by all
+// instances and only then in the storage. Orders found in the remote tier are copied to the
+// local one, and every tier keeps them for its own TTL. Changes are broadcast with the
+// invalidator, so that the other instances drop their local copies
+type TieredCacheAdapter struct {
+	local       Tier
+	remote      Tier
+	invalidator ports.CacheInvalidator
+}
+
+// NewTieredCacheAdapter creates the adapter, a nil invalidator leaves the local
+// copies of other instances to expire by their TTL
+func NewTieredCacheAdapter(local, remote Tier, invalidator ports.CacheInvalidator) *TieredCacheAdapter {
+	return &TieredCacheAdapter{
+		local:       local,
+		remote:      remote,
+		invalidator: invalidator,
+	}
+}
+
+func (a *TieredCacheAdapter) GetOrder(key string) (*models.Order, error) {
+	order, err := a.local.GetOrder(key)
+	if err == nil {
+		return order, nil
+	}
+
+	order, err = a.remote.GetOrder(key)
+	if err != nil {
+		return nil, err
+	}
+	_ = a.local.SaveOrder(key, order)
+
+	return order, nil
+}
+
+// GetOrLoadOrder reports CacheRemote for orders found in the remote tier only.
+// Concurrent misses share one remote lookup, and the storage is queried on a remote miss
+func (a *TieredCacheAdapter) GetOrLoadOrder(
+	ctx context.Context, id string, loader func(ctx context.Context) (*models.Order, error),
+) (*models.Order, models.CacheStatus, error) {
+	// the load may outlive this call if ctx is canceled, hence the atomic
+	var loaded atomic.Value
+	order, status, err := a.local.GetOrLoadOrder(ctx, id, func(ctx context.Context) (*models.Order, error) {
+		order, status, err := a.remote.GetOrLoadOrder(ctx, id, loader)
+		loaded.Store(status)
+		return order, err
+	})
+
+	// remoteStatus is only set for the caller whose load ran
+	remoteStatus, _ := loaded.Load().(models.CacheStatus)
+	switch {
+	case status != models.CacheMiss || remoteStatus == "":
+		return order, status, err
+	case remoteStatus == models.CacheHit:
+		return order, models.CacheRemote, err
+	default:
+		return order, remoteStatus, err
+	}
+}
+
+// SaveOrder stores the order in both tiers and drops the copies of other instances
+func (a *TieredCacheAdapter) SaveOrder(key string, val *models.Order) error {
+	if err := a.remote.SaveOrder(key, val); err != nil {
+		return err
+	}
+	if err := a.local.SaveOrder(key, val); err != nil {
+		return err
+	}
+
+	return a.publish(key)
+}
+
+func (a *TieredCacheAdapter) HasOrder(key string) bool {
+	return a.local.HasOrder(key) || a.remote.HasOrder(key)
+}
+
+// RefreshOrder stores the order in both tiers without publishing, so that the invalidation
+// that every instance receives is not echoed back to all of them
+func (a *TieredCacheAdapter) RefreshOrder(key string, val *models.Order) error {
+	if err := a.remote.RefreshOrder(key, val); err != nil {
+		return err
+	}
+	return a.local.RefreshOrder(key, val)
+}
+
+// InvalidateNotFound drops the not found results of both tiers of this instance without publishing,
+// it runs for every stored batch. Other instances keep theirs for the not found TTL
+// unless they receive the invalidations of the service
+func (a *TieredCacheAdapter) InvalidateNotFound(ids ...string) {
+	a.local.InvalidateNotFound(ids...)
+	a.remote.InvalidateNotFound(ids...)
+}
+
+// InvalidateOrders drops the orders from both tiers of this instance only,
+// it is meant for invalidations that every instance receives
+func (a *TieredCacheAdapter) InvalidateOrders(ids ...string) {
+	a.local.InvalidateOrders(ids...)
+	a.remote.InvalidateOrders(ids...)
+}
+
+func (a *TieredCacheAdapter) publish(ids ...string) error {
+	if a.invalidator == nil {
+		return nil
+	}
+	return a.invalidator.PublishInvalidation(context.Background(), ids...)
+}
+
+// ListenInvalidations drops the local copies of the orders changed by other instances
+// until ctx is done
+func (a *TieredCacheAdapter) ListenInvalidations(ctx context.Context) error {
+	if a.invalidator == nil {
+		return nil
+	}
+
+	return a.invalidator.SubscribeInvalidations(ctx, a.dropLocal)
+}
+
+func (a *TieredCacheAdapter) dropLocal(ids []string) {
+	a.local.InvalidateOrders(ids...)
+}
+
+func (a *TieredCacheAdapter) PeekOrder(key string) (*models.CacheEntry, error) {
+	entry, err := a.local.PeekOrder(key)
+	if errors.Is(err, errs.ErrOrderNotFound) {
+		return a.remote.PeekOrder(key)
+	}
+	return entry, err
+}
+
+// Keys lists the local tier
+func (a *TieredCacheAdapter) Keys(limit int) []string {
+	return a.local.Keys(limit)
+}
+
+// DeleteOrder removes the order from both tiers and drops the copies of other instances,
+// it returns errs.ErrOrderNotFound only if neither tier had it
+func (a *TieredCacheAdapter) DeleteOrder(key string) error {
+	localErr := a.local.DeleteOrder(key)
+	if localErr != nil && !errors.Is(localErr, errs.ErrOrderNotFound) {
+		return localErr
+	}
+	remoteErr := a.remote.DeleteOrder(key)
+	if remoteErr != nil && !errors.Is(remoteErr, errs.ErrOrderNotFound) {
+		return remoteErr
+	}
+	if err := a.publish(key); err != nil {
+		return err
+	}
+	if localErr != nil && remoteErr != nil {
+		return errs.ErrOrderNotFound
+	}
+
+	return nil
+}
+
+// Purge empties the local tier and the remote one, which is shared by all instances.
+// Nothing is published, the local tiers of other instances keep serving their copies until the local TTL
+func (a *TieredCacheAdapter) Purge() {
+	a.local.Purge()
+	a.remote.Purge()
+}
+
+// Stats reports the local tier, whose misses are the lookups that reached the remote one,
+// with the stats of the remote tier
+func (a *TieredCacheAdapter) Stats() models.CacheStats {
+	stats := a.local.Stats()
+	remote := a.remote.Stats()
+	stats.Remote = &remote
+
+	return stats
+}
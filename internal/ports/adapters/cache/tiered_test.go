@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	lrucache "github.com/jaam8/wb_tech_school_l0/pkg/lru-cache"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testInstance struct {
+	local   *InMemoryCacheAdapter
+	remote  *RedisCacheAdapter
+	adapter *TieredCacheAdapter
+}
+
+func newTestInstance(client *goredis.Client) testInstance {
+	local := NewInMemoryCacheAdapter(lrucache.NewInMemoryCache[string, *models.Order](10, time.Minute), nil).
+		WithNotFoundCache(lrucache.NewInMemoryCache[string, struct{}](10, time.Minute), time.Minute)
+	remote := NewRedisCacheAdapter(client, jsonCodec{}, "test:", FixedTTL(time.Hour)).
+		WithNotFoundTTL(time.Minute)
+
+	return testInstance{
+		local:   local,
+		remote:  remote,
+		adapter: NewTieredCacheAdapter(local, remote, NewRedisInvalidator(client, "test:invalidate")),
+	}
+}
+
+func TestTieredCacheAdapterGetOrLoadOrder(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	first, second := newTestInstance(client), newTestInstance(client)
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context) (*models.Order, error) {
+		loads.Add(1)
+		return newTestOrder("first"), nil
+	}
+
+	tests := []struct {
+		name     string
+		instance testInstance
+		want     models.CacheStatus
+	}{
+		{name: "loaded from storage", instance: first, want: models.CacheMiss},
+		{name: "local hit", instance: first, want: models.CacheHit},
+		{name: "remote hit", instance: second, want: models.CacheRemote},
+		{name: "promoted to local", instance: second, want: models.CacheHit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, status, err := tt.instance.adapter.GetOrLoadOrder(ctx, "first", loader)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, status)
+			assert.Equal(t, "first", order.OrderUID)
+		})
+	}
+	assert.Equal(t, int32(1), loads.Load())
+
+	// every tier keeps the order for its own TTL
+	assert.Equal(t, time.Hour, server.TTL("test:order:first"))
+	entry, err := second.local.PeekOrder("first")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), entry.ExpiresAt, time.Second)
+}
+
+func TestTieredCacheAdapterNotFound(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	instance := newTestInstance(client)
+
+	notFound := func(ctx context.Context) (*models.Order, error) {
+		return nil, errs.ErrOrderNotFound
+	}
+
+	_, status, err := instance.adapter.GetOrLoadOrder(ctx, "missing", notFound)
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+	assert.Equal(t, models.CacheMiss, status)
+	assert.True(t, server.Exists("test:notfound:missing"))
+
+	_, status, err = instance.adapter.GetOrLoadOrder(ctx, "missing", notFound)
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+	assert.Equal(t, models.CacheNegative, status)
+
+	instance.adapter.InvalidateNotFound("missing")
+	assert.False(t, server.Exists("test:notfound:missing"))
+	_, status, err = instance.adapter.GetOrLoadOrder(ctx, "missing", func(ctx context.Context) (*models.Order, error) {
+		return newTestOrder("missing"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.CacheMiss, status)
+}
+
+func TestTieredCacheAdapterInvalidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestRedis(t)
+	first, second := newTestInstance(client), newTestInstance(client)
+
+	go func() { _ = first.adapter.ListenInvalidations(ctx) }()
+	go func() { _ = second.adapter.ListenInvalidations(ctx) }()
+	// wait for both subscriptions, pub/sub messages sent before them are lost
+	require.Eventually(t, func() bool {
+		counts, err := client.PubSubNumSub(ctx, "test:invalidate").Result()
+		return err == nil && counts["test:invalidate"] == 2
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, first.adapter.SaveOrder("first", newTestOrder("first")))
+	_, err := second.adapter.GetOrder("first")
+	require.NoError(t, err)
+
+	updated := newTestOrder("first")
+	updated.TrackNumber = "UPDATED"
+	require.NoError(t, first.adapter.SaveOrder("first", updated))
+
+	// the publisher keeps its own copy, the other instance drops its stale one
+	order, err := first.local.GetOrder("first")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATED", order.TrackNumber)
+	require.Eventually(t, func() bool {
+		_, err := second.local.GetOrder("first")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	order, err = second.adapter.GetOrder("first")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATED", order.TrackNumber)
+}
+
+func TestTieredCacheAdapterRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestRedis(t)
+	first, second := newTestInstance(client), newTestInstance(client)
+
+	go func() { _ = second.adapter.ListenInvalidations(ctx) }()
+	require.Eventually(t, func() bool {
+		counts, err := client.PubSubNumSub(ctx, "test:invalidate").Result()
+		return err == nil && counts["test:invalidate"] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, second.local.SaveOrder("first", newTestOrder("first")))
+	require.NoError(t, second.local.SaveOrder("marker", newTestOrder("marker")))
+	assert.True(t, second.adapter.HasOrder("first"))
+	assert.False(t, first.adapter.HasOrder("first"))
+
+	updated := newTestOrder("first")
+	updated.TrackNumber = "UPDATED"
+	require.NoError(t, first.adapter.RefreshOrder("first", updated))
+	first.adapter.InvalidateNotFound("first")
+
+	order, err := first.local.GetOrder("first")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATED", order.TrackNumber)
+	order, err = first.remote.GetOrder("first")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATED", order.TrackNumber)
+
+	// messages arrive in order, once the marker is dropped a refresh message would have been handled
+	require.NoError(t, first.adapter.SaveOrder("marker", newTestOrder("marker")))
+	require.Eventually(t, func() bool {
+		_, err := second.local.GetOrder("marker")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	_, err = second.local.GetOrder("first")
+	require.NoError(t, err, "a refresh or a not found invalidation should not drop the copies of other instances")
+}
+
+func TestTieredCacheAdapterAdmin(t *testing.T) {
+	_, client := newTestRedis(t)
+	instance := newTestInstance(client)
+	require.NoError(t, instance.remote.SaveOrder("remote", newTestOrder("remote")))
+	require.NoError(t, instance.adapter.SaveOrder("both", newTestOrder("both")))
+
+	entry, err := instance.adapter.PeekOrder("remote")
+	require.NoError(t, err)
+	assert.Equal(t, "remote", entry.Value.OrderUID)
+	stats := instance.adapter.Stats()
+	assert.Equal(t, 1, stats.Len)
+	require.NotNil(t, stats.Remote)
+	assert.Equal(t, 2, stats.Remote.Len)
+	assert.Equal(t, []string{"both"}, instance.adapter.Keys(10))
+
+	require.NoError(t, instance.adapter.DeleteOrder("remote"))
+	assert.ErrorIs(t, instance.adapter.DeleteOrder("remote"), errs.ErrOrderNotFound)
+
+	instance.adapter.Purge()
+	_, err = instance.adapter.PeekOrder("both")
+	assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresAuditAdapter struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresAuditAdapter(pool *pgxpool.Pool) *PostgresAuditAdapter {
+	return &PostgresAuditAdapter{
+		pool: pool,
+	}
+}
+
+func (a *PostgresAuditAdapter) SaveAuditRecords(ctx context.Context, records ...*models.AuditRecord) error {
+	rows := make([][]any, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, []any{
+			r.OccurredAt, r.Caller, nullIfEmpty(r.ClaimedCaller), r.Action, nullIfEmpty(r.OrderUID),
+			r.Route, r.Result, r.Fields, r.Details,
+		})
+	}
+
+	_, err := a.pool.CopyFrom(ctx,
+		pgx.Identifier{"audit_log"},
+		[]string{
+			"occurred_at", "caller", "claimed_caller", "action", "order_uid",
+			"route", "result", "fields", "details",
+		},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save audit records: %w", err)
+	}
+
+	return nil
+}
+
+func (a *PostgresAuditAdapter) GetAuditRecords(
+	ctx context.Context,
+	filter models.AuditFilter,
+) ([]models.AuditRecord, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	addCond := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.OrderUID != "" {
+		addCond("order_uid = $%d", filter.OrderUID)
+	}
+	if filter.Caller != "" {
+		addCond("caller = $%d", filter.Caller)
+	}
+	if filter.ClaimedCaller != "" {
+		addCond("claimed_caller = $%d", filter.ClaimedCaller)
+	}
+	if !filter.From.IsZero() {
+		addCond("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCond("occurred_at < $%d", filter.To)
+	}
+
+	query := `
+	SELECT id, occurred_at, caller, COALESCE(claimed_caller, '') AS claimed_caller,
+	       action, COALESCE(order_uid, '') AS order_uid,
+	       route, result, COALESCE(fields, '{}') AS fields, COALESCE(details, '') AS details
+	FROM audit_log
+`
+	if len(conds) > 0 {
+		query += "WHERE " + strings.Join(conds, " AND ") + "\n"
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf("ORDER BY occurred_at DESC\nLIMIT $%d", len(args))
+
+	rows, err := a.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit records: %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.AuditRecord])
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// the staging tables live as long as the connection and are emptied by every commit,
+// which spares the catalog a create and a drop per batch
+const createStagingQuery = `
+	CREATE TEMP TABLE IF NOT EXISTS staged_orders (
+		order_uid TEXT NOT NULL,
+		track_number TEXT NOT NULL,
+		entry TEXT NOT NULL,
+		locale TEXT NOT NULL,
+		internal_signature TEXT,
+		customer_id TEXT NOT NULL,
+		delivery_service TEXT NOT NULL,
+		shardkey TEXT NOT NULL,
+		sm_id INTEGER NOT NULL,
+		date_created TIMESTAMP NOT NULL,
+		oof_shard TEXT NOT NULL,
+
+		-- COPY evaluates the default row by row, so the ids follow the order of the batch
+		delivery_id INTEGER NOT NULL DEFAULT nextval(pg_get_serial_sequence('deliveries', 'id')),
+		delivery_name TEXT NOT NULL,
+		delivery_phone TEXT NOT NULL,
+		delivery_zip TEXT NOT NULL,
+		delivery_city TEXT NOT NULL,
+		delivery_address TEXT NOT NULL,
+		delivery_region TEXT NOT NULL,
+		delivery_email TEXT NOT NULL,
+
+		payment_transaction TEXT NOT NULL,
+		payment_request_id TEXT,
+		payment_currency TEXT NOT NULL,
+		payment_provider TEXT NOT NULL,
+		payment_amount INTEGER NOT NULL,
+		payment_dt BIGINT NOT NULL,
+		payment_bank TEXT NOT NULL,
+		payment_delivery_cost INTEGER NOT NULL,
+		payment_goods_total INTEGER NOT NULL,
+		payment_custom_fee INTEGER NOT NULL
+	) ON COMMIT DELETE ROWS;
+
+	CREATE TEMP TABLE IF NOT EXISTS staged_order_items (LIKE order_items) ON COMMIT DELETE ROWS;
+`
+
+// the merge runs the same inserts as insertOrders, so it fails on the same conflicts
+const mergeStagingQuery = `
+	INSERT INTO deliveries (id, name, phone, zip, city, address, region, email)
+	SELECT delivery_id, delivery_name, delivery_phone, delivery_zip,
+	       delivery_city, delivery_address, delivery_region, delivery_email
+	FROM staged_orders;
+
+	INSERT INTO payments (transaction, request_id, currency, provider, amount,
+	                      payment_dt, bank, delivery_cost, goods_total, custom_fee)
+	SELECT payment_transaction, payment_request_id, payment_currency, payment_provider,
+	       payment_amount, payment_dt, payment_bank, payment_delivery_cost,
+	       payment_goods_total, payment_custom_fee
+	FROM staged_orders;
+
+	INSERT INTO order_uids (order_uid)
+	SELECT order_uid FROM staged_orders;
+
+	INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_transaction,
+	                    locale, internal_signature, customer_id, delivery_service,
+	                    shardkey, sm_id, date_created, oof_shard)
+	SELECT order_uid, track_number, entry, delivery_id, payment_transaction,
+	       locale, internal_signature, customer_id, delivery_service,
+	       shardkey, sm_id, date_created, oof_shard
+	FROM staged_orders;
+
+	INSERT INTO order_items (order_uid, date_created, line_no, chrt_id, track_number, price, rid,
+	                         name, sale, size, total_price, nm_id, brand, status)
+	SELECT order_uid, date_created, line_no, chrt_id, track_number, price, rid,
+	       name, sale, size, total_price, nm_id, brand, status
+	FROM staged_order_items;
+`
+
+var stagedOrderColumns = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature",
+	"customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city",
+	"delivery_address", "delivery_region", "delivery_email",
+
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+	"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+	"payment_goods_total", "payment_custom_fee",
+}
+
+var stagedItemColumns = []string{
+	"order_uid", "date_created", "line_no", "chrt_id", "track_number", "price", "rid",
+	"name", "sale", "size", "total_price", "nm_id", "brand", "status",
+}
+
+// copyOrders stages the whole batch with two COPYs and merges it with set-based inserts,
+// it costs four round-trips however large the batch is
+func copyOrders(ctx context.Context, tx pgx.Tx, orders []*models.Order) error {
+	if _, err := tx.Exec(ctx, createStagingQuery); err != nil {
+		return fmt.Errorf("failed to create staging tables: %w", err)
+	}
+
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"staged_orders"}, stagedOrderColumns,
+		pgx.CopyFromSlice(len(orders), func(i int) ([]any, error) {
+			order := orders[i]
+			return []any{
+				order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+				order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID,
+				order.DateCreated, order.OofShard,
+
+				order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City,
+				order.Delivery.Address, order.Delivery.Region, order.Delivery.Email,
+
+				order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency,
+				order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt,
+				order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal,
+				order.Payment.CustomFee,
+			}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy orders: %w", err)
+	}
+
+	items := make([][]any, 0, len(orders))
+	for _, order := range orders {
+		for i, item := range order.Items {
+			items = append(items, []any{
+				order.OrderUID, order.DateCreated, i + 1, item.ChrtID, item.TrackNumber, item.Price, item.Rid,
+				item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status,
+			})
+		}
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"staged_order_items"}, stagedItemColumns, pgx.CopyFromRows(items))
+	if err != nil {
+		return fmt.Errorf("failed to copy order items: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, mergeStagingQuery); err != nil {
+		return fmt.Errorf("failed to merge staged orders: %w", err)
+	}
+
+	return nil
+}
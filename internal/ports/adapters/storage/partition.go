@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	ordersTable     = "orders"
+	orderItemsTable = "order_items"
+	partitionLayout = "2006_01"
+)
+
+// PostgresPartitionAdapter maintains the monthly partitions of orders and order_items,
+// which are named after the table and the month, like orders_2024_01
+type PostgresPartitionAdapter struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresPartitionAdapter(pool *pgxpool.Pool) *PostgresPartitionAdapter {
+	return &PostgresPartitionAdapter{
+		pool: pool,
+	}
+}
+
+func partitionName(table string, month time.Time) string {
+	return pgx.Identifier{table + "_" + month.Format(partitionLayout)}.Sanitize()
+}
+
+// lockPartitions serializes the maintenance of all instances until the end of tx
+func lockPartitions(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('orders_partitions'))")
+	if err != nil {
+		return fmt.Errorf("failed to lock partitions: %w", err)
+	}
+	return nil
+}
+
+// ListOrderPartitions skips the default partition and the ones not named by the convention
+func (a *PostgresPartitionAdapter) ListOrderPartitions(ctx context.Context) ([]time.Time, error) {
+	query := `
+	SELECT c.relname
+	FROM pg_inherits i
+	JOIN pg_class c ON c.oid = i.inhrelid
+	WHERE i.inhparent = 'orders'::regclass
+`
+	rows, err := a.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	months := make([]time.Time, 0, len(names))
+	for _, name := range names {
+		suffix, ok := strings.CutPrefix(name, ordersTable+"_")
+		if !ok {
+			continue
+		}
+		month, err := time.Parse(partitionLayout, suffix)
+		if err != nil {
+			continue
+		}
+		months = append(months, month)
+	}
+	slices.SortFunc(months, time.Time.Compare)
+
+	return months, nil
+}
+
+// CreateOrderPartition creates the partitions of the month. Orders of the month that landed
+// in the default partitions before are moved to the new ones, as Postgres refuses to create
+// a partition while the default one holds rows of its range
+func (a *PostgresPartitionAdapter) CreateOrderPartition(ctx context.Context, month time.Time) error {
+	tx, err := a.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err = lockPartitions(ctx, tx); err != nil {
+		return err
+	}
+
+	// the items go first, deleting the orders would cascade to them
+	for _, table := range []string{orderItemsTable, ordersTable} {
+		query := fmt.Sprintf(`
+			CREATE TEMP TABLE %[1]s ON COMMIT DROP AS
+			SELECT * FROM %[2]s WITH NO DATA`,
+			pgx.Identifier{"moved_" + table}.Sanitize(), table)
+		if _, err = tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to move default partition of %s: %w", table, err)
+		}
+
+		query = fmt.Sprintf(`
+			WITH moved AS (
+				DELETE FROM %[2]s WHERE date_created >= $1 AND date_created < $2 RETURNING *
+			)
+			INSERT INTO %[1]s SELECT * FROM moved`,
+			pgx.Identifier{"moved_" + table}.Sanitize(), pgx.Identifier{table + "_default"}.Sanitize())
+		if _, err = tx.Exec(ctx, query, month, month.AddDate(0, 1, 0)); err != nil {
+			return fmt.Errorf("failed to move default partition of %s: %w", table, err)
+		}
+	}
+
+	from, to := month.Format(time.DateTime), month.AddDate(0, 1, 0).Format(time.DateTime)
+	for _, table := range []string{ordersTable, orderItemsTable} {
+		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			partitionName(table, month), table, from, to)
+		if _, err = tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to create partition of %s: %w", table, err)
+		}
+
+		query = fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", table, pgx.Identifier{"moved_" + table}.Sanitize())
+		if _, err = tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to move orders to partition of %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (a *PostgresPartitionAdapter) ForEachOrderInPartition(
+	ctx context.Context, month time.Time, fn func(order *models.Order) error,
+) error {
+	query := selectOrdersQuery + `
+	WHERE o.date_created >= $1 AND o.date_created < $2
+`
+	rows, err := a.pool.Query(ctx, query, month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan order: %w", err)
+		}
+		if err = fn(order); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// RetireOrderPartition detaches the partitions of the month and frees the ids of their orders.
+// With drop they are dropped along with the deliveries and payments of their orders,
+// otherwise they are kept as standalone tables without the foreign key between them
+func (a *PostgresPartitionAdapter) RetireOrderPartition(ctx context.Context, month time.Time, drop bool) error {
+	tx, err := a.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err = lockPartitions(ctx, tx); err != nil {
+		return err
+	}
+
+	orders, items := partitionName(ordersTable, month), partitionName(orderItemsTable, month)
+	query := fmt.Sprintf(`
+		CREATE TEMP TABLE retired_orders ON COMMIT DROP AS
+		SELECT order_uid, delivery_id, payment_transaction FROM %s`, orders)
+	if _, err = tx.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to collect retired orders: %w", err)
+	}
+
+	// the items go first, a partition of orders cannot be detached while rows reference it
+	if _, err = tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", orderItemsTable, items)); err != nil {
+		return fmt.Errorf("failed to detach partition of %s: %w", orderItemsTable, err)
+	}
+	if drop {
+		_, err = tx.Exec(ctx, "DROP TABLE "+items)
+	} else {
+		err = dropForeignKeys(ctx, tx, items)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retire partition of %s: %w", orderItemsTable, err)
+	}
+
+	if _, err = tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", ordersTable, orders)); err != nil {
+		return fmt.Errorf("failed to detach partition of %s: %w", ordersTable, err)
+	}
+	if drop {
+		query = "DROP TABLE " + orders + `;
+			DELETE FROM deliveries WHERE id IN (SELECT delivery_id FROM retired_orders);
+			DELETE FROM payments WHERE transaction IN (SELECT payment_transaction FROM retired_orders);`
+		if _, err = tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to drop partition of %s: %w", ordersTable, err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM order_uids WHERE order_uid IN (SELECT order_uid FROM retired_orders)")
+	if err != nil {
+		return fmt.Errorf("failed to free ids of retired orders: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func dropForeignKeys(ctx context.Context, tx pgx.Tx, table string) error {
+	rows, err := tx.Query(ctx,
+		"SELECT conname FROM pg_constraint WHERE conrelid = $1::regclass AND contype = 'f'", table)
+	if err != nil {
+		return err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		query := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, pgx.Identifier{name}.Sanitize())
+		if _, err = tx.Exec(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
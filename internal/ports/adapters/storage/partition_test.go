@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresPartitionAdapter(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPostgres(t)
+	orders := NewPostgresAdapter(pool)
+	partitions := NewPostgresPartitionAdapter(pool)
+
+	tests := []struct {
+		name  string
+		month time.Time
+		drop  bool
+	}{
+		{name: "drop", month: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC), drop: true},
+		{name: "detach", month: time.Date(2031, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+partitionName(orderItemsTable, tt.month))
+				_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+partitionName(ordersTable, tt.month))
+			})
+
+			require.NoError(t, partitions.CreateOrderPartition(ctx, tt.month))
+			require.NoError(t, partitions.CreateOrderPartition(ctx, tt.month))
+			months, err := partitions.ListOrderPartitions(ctx)
+			require.NoError(t, err)
+			assert.Contains(t, months, tt.month)
+
+			order := newTestOrders(1)[0]
+			order.DateCreated = tt.month.Add(36 * time.Hour)
+			require.NoError(t, orders.SaveOrders(ctx, order))
+
+			var streamed []*models.Order
+			require.NoError(t, partitions.ForEachOrderInPartition(ctx, tt.month, func(order *models.Order) error {
+				streamed = append(streamed, order)
+				return nil
+			}))
+			require.Len(t, streamed, 1)
+			assert.Equal(t, order.OrderUID, streamed[0].OrderUID)
+			assert.Equal(t, order.Items, streamed[0].Items)
+
+			require.NoError(t, partitions.RetireOrderPartition(ctx, tt.month, tt.drop))
+			months, err = partitions.ListOrderPartitions(ctx)
+			require.NoError(t, err)
+			assert.NotContains(t, months, tt.month)
+			_, err = orders.GetOrder(ctx, order.OrderUID)
+			assert.ErrorIs(t, err, errs.ErrOrderNotFound)
+
+			var uids int
+			require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM order_uids WHERE order_uid = $1",
+				order.OrderUID).Scan(&uids))
+			assert.Zero(t, uids, "the id of a retired order should be freed")
+
+			var detached bool
+			require.NoError(t, pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL",
+				partitionName(ordersTable, tt.month)).Scan(&detached))
+			assert.Equal(t, !tt.drop, detached)
+
+			var payments int
+			require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM payments WHERE transaction = $1",
+				order.Payment.Transaction).Scan(&payments))
+			if tt.drop {
+				assert.Zero(t, payments)
+			} else {
+				assert.Equal(t, 1, payments)
+			}
+		})
+	}
+}
+
+func TestPostgresPartitionAdapterMovesDefault(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPostgres(t)
+	orders := NewPostgresAdapter(pool)
+	partitions := NewPostgresPartitionAdapter(pool)
+
+	month := time.Date(2032, 3, 1, 0, 0, 0, 0, time.UTC)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+partitionName(orderItemsTable, month))
+		_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+partitionName(ordersTable, month))
+	})
+
+	// no partition exists for the month yet, the order lands in the default one
+	order := newTestOrders(1)[0]
+	order.DateCreated = month.Add(36 * time.Hour)
+	require.NoError(t, orders.SaveOrders(ctx, order))
+
+	require.NoError(t, partitions.CreateOrderPartition(ctx, month))
+
+	var moved []*models.Order
+	require.NoError(t, partitions.ForEachOrderInPartition(ctx, month, func(order *models.Order) error {
+		moved = append(moved, order)
+		return nil
+	}))
+	require.Len(t, moved, 1)
+	assert.Equal(t, order.Items, moved[0].Items)
+
+	var inDefault int
+	require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM orders_default").Scan(&inDefault))
+	assert.Zero(t, inDefault)
+	var inPartition int
+	require.NoError(t, pool.QueryRow(ctx,
+		"SELECT count(*) FROM "+partitionName(orderItemsTable, month)).Scan(&inPartition))
+	assert.Len(t, order.Items, inPartition)
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jaam8/wb_tech_school_l0/internal/models"
 	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
@@ -12,6 +13,9 @@ import (
 
 type PostgresAdapter struct {
 	pool *pgxpool.Pool
+
+	// copyMinBatch is the smallest batch SaveOrders stages through COPY, zero if disabled
+	copyMinBatch int
 }
 
 func NewPostgresAdapter(pool *pgxpool.Pool) *PostgresAdapter {
@@ -20,8 +24,16 @@ func NewPostgresAdapter(pool *pgxpool.Pool) *PostgresAdapter {
 	}
 }
 
-func (a *PostgresAdapter) GetOrder(ctx context.Context, id string) (*models.Order, error) {
-	query := `
+// WithCopy makes SaveOrders write batches of at least minBatch orders with COPY,
+// see copyOrders. It must be called before the adapter is used
+func (a *PostgresAdapter) WithCopy(minBatch int) *PostgresAdapter {
+	a.copyMinBatch = minBatch
+	return a
+}
+
+// selectOrdersQuery reads orders with their delivery, payment and items in a single round-trip,
+// the items are aggregated into a JSON array in the order they were ingested
+const selectOrdersQuery = `
 	SELECT
     o.order_uid,
     o.track_number,
@@ -52,16 +64,35 @@ func (a *PostgresAdapter) GetOrder(ctx context.Context, id string) (*models.Orde
     p.bank,
     p.delivery_cost,
     p.goods_total,
-    p.custom_fee
-	
+    p.custom_fee,
+
+    COALESCE(i.items, '[]')
+
 	FROM orders o
 	JOIN deliveries d ON d.id = o.delivery_id
 	JOIN payments p ON p.transaction = o.payment_transaction
-	WHERE o.order_uid = $1
+	LEFT JOIN LATERAL (
+		SELECT json_agg(json_build_object(
+			'chrt_id', oi.chrt_id,
+			'track_number', oi.track_number,
+			'price', oi.price,
+			'rid', oi.rid,
+			'name', oi.name,
+			'sale', oi.sale,
+			'size', oi.size,
+			'total_price', oi.total_price,
+			'nm_id', oi.nm_id,
+			'brand', oi.brand,
+			'status', oi.status
+		) ORDER BY oi.line_no) AS items
+		FROM order_items oi
+		WHERE oi.order_uid = o.order_uid AND oi.date_created = o.date_created
+	) i ON true
 `
 
+func scanOrder(row pgx.Row) (*models.Order, error) {
 	var order models.Order
-	err := a.pool.QueryRow(ctx, query, id).Scan(
+	err := row.Scan(
 		&order.OrderUID, &order.TrackNumber,
 		&order.Entry, &order.Locale,
 		&order.InternalSignature, &order.CustomerID,
@@ -79,34 +110,68 @@ func (a *PostgresAdapter) GetOrder(ctx context.Context, id string) (*models.Orde
 		&order.Payment.Amount, &order.Payment.PaymentDt,
 		&order.Payment.Bank, &order.Payment.DeliveryCost,
 		&order.Payment.GoodsTotal, &order.Payment.CustomFee,
+
+		&order.Items,
 	)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errs.ErrOrderNotFound
-		}
 		return nil, err
 	}
 
-	query = `
-	SELECT i.* 
-	FROM orders o 
-	JOIN order_items oi ON oi.order_uid = o.order_uid
-	JOIN items i ON i.chrt_id = oi.item_chrt_id
+	return &order, nil
+}
+
+func (a *PostgresAdapter) GetOrder(ctx context.Context, id string) (*models.Order, error) {
+	query := selectOrdersQuery + `
 	WHERE o.order_uid = $1
 `
-	rows, err := a.pool.Query(ctx, query, order.OrderUID)
+
+	order, err := scanOrder(a.pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errs.ErrOrderItemsNotFound
+			return nil, errs.ErrOrderNotFound
 		}
+		return nil, fmt.Errorf("failed to query order: %w", err)
+	}
+	// every ingested order has items, an order without them was written partially
+	if len(order.Items) == 0 {
+		return nil, fmt.Errorf("failed to get order %s: %w", id, errs.ErrOrderItemsNotFound)
 	}
-	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.Item])
+
+	return order, nil
+}
+
+func (a *PostgresAdapter) GetRecentOrderUIDs(ctx context.Context, limit int) ([]string, error) {
+	query := `
+	SELECT order_uid
+	FROM orders
+	ORDER BY date_created DESC
+	LIMIT $1
+`
+	rows, err := a.pool.Query(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
-	order.Items = items
 
-	return &order, nil
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (a *PostgresAdapter) ForEachOrderUID(ctx context.Context, fn func(id string)) error {
+	query := `
+	SELECT order_uid
+	FROM orders
+`
+	rows, err := a.pool.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	var id string
+	_, err = pgx.ForEachRow(rows, []any{&id}, func() error {
+		fn(id)
+		return nil
+	})
+
+	return err
 }
 
 func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Order) error {
@@ -118,6 +183,22 @@ func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Orde
 		_ = tx.Rollback(ctx)
 	}()
 
+	if a.copyMinBatch > 0 && len(orders) >= a.copyMinBatch {
+		err = copyOrders(ctx, tx, orders)
+	} else {
+		err = insertOrders(ctx, tx, orders)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertOrders writes the orders one by one, it costs a few round-trips per order
+func insertOrders(ctx context.Context, tx pgx.Tx, orders []*models.Order) error {
+	var err error
+
 	// queries
 	deliveriesQuery := `
 		INSERT INTO deliveries (name, phone, zip, city, address, region, email)
@@ -129,6 +210,11 @@ func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Orde
                               payment_dt, bank, delivery_cost, goods_total, custom_fee)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
+	// order_uids keeps the ids unique across the partitions of orders
+	orderUIDsQuery := `
+		INSERT INTO order_uids (order_uid)
+		VALUES ($1)
+	`
 	ordersQuery := `
         INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_transaction,
                             locale, internal_signature, customer_id, delivery_service,
@@ -136,10 +222,9 @@ func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Orde
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	itemsQuery := `
-		INSERT INTO items (chrt_id, track_number, price, rid, name, sale,
-						   size, total_price, nm_id, brand, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (chrt_id) DO NOTHING
+		INSERT INTO order_items (order_uid, date_created, line_no, chrt_id, track_number, price, rid,
+		                         name, sale, size, total_price, nm_id, brand, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
 	for _, order := range orders {
@@ -173,6 +258,11 @@ func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Orde
 			return err
 		}
 
+		_, err = tx.Exec(ctx, orderUIDsQuery, order.OrderUID)
+		if err != nil {
+			return err
+		}
+
 		_, err = tx.Exec(ctx, ordersQuery,
 			order.OrderUID,
 			order.TrackNumber,
@@ -193,8 +283,11 @@ func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Orde
 		}
 
 		batch := &pgx.Batch{}
-		for _, item := range order.Items {
+		for i, item := range order.Items {
 			batch.Queue(itemsQuery,
+				order.OrderUID,
+				order.DateCreated,
+				i+1,
 				item.ChrtID,
 				item.TrackNumber,
 				item.Price,
@@ -207,12 +300,6 @@ func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Orde
 				item.Brand,
 				item.Status,
 			)
-
-			batch.Queue(`
-        INSERT INTO order_items (order_uid, item_chrt_id)
-        VALUES ($1,$2)`,
-				order.OrderUID, item.ChrtID,
-			)
 		}
 
 		err = tx.SendBatch(ctx, batch).Close()
@@ -221,5 +308,5 @@ func (a *PostgresAdapter) SaveOrders(ctx context.Context, orders ...*models.Orde
 		}
 	}
 
-	return tx.Commit(ctx)
+	return nil
 }
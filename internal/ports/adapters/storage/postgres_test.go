@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPostgres connects to the database of TEST_POSTGRES_DSN, migrates it and empties
+// the order tables, so the DSN must point to a disposable database
+func newTestPostgres(tb testing.TB) *pgxpool.Pool {
+	tb.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		tb.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := goose.OpenDBWithDriver("pgx", dsn)
+	require.NoError(tb, err)
+	defer db.Close()
+	require.NoError(tb, goose.SetDialect("postgres"))
+	require.NoError(tb, goose.Up(db, "../../../../migrations"))
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	require.NoError(tb, err)
+	tb.Cleanup(pool.Close)
+	truncateOrders(tb, pool)
+
+	return pool
+}
+
+func truncateOrders(tb testing.TB, pool *pgxpool.Pool) {
+	tb.Helper()
+
+	_, err := pool.Exec(context.Background(),
+		"TRUNCATE order_items, orders, order_uids, payments, deliveries RESTART IDENTITY CASCADE")
+	require.NoError(tb, err)
+}
+
+func newTestOrders(n int) []*models.Order {
+	orders := make([]*models.Order, n)
+	for i := range orders {
+		order := models.GenerateFakeOrder()
+		// TIMESTAMP keeps microseconds
+		order.DateCreated = order.DateCreated.Truncate(time.Microsecond)
+		orders[i] = &order
+	}
+	return orders
+}
+
+func TestPostgresAdapterSaveOrders(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPostgres(t)
+
+	tests := []struct {
+		name    string
+		adapter *PostgresAdapter
+	}{
+		{name: "insert", adapter: NewPostgresAdapter(pool)},
+		{name: "copy", adapter: NewPostgresAdapter(pool).WithCopy(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncateOrders(t, pool)
+
+			orders := newTestOrders(3)
+			// the same chrt_id with another payload in two orders and twice in one order
+			orders[1].Items[0].ChrtID = orders[0].Items[0].ChrtID
+			orders[1].Items[0].Name = "Another payload"
+			orders[2].Items = append(orders[2].Items, orders[2].Items[0])
+			require.NoError(t, tt.adapter.SaveOrders(ctx, orders...))
+
+			for _, want := range orders {
+				got, err := tt.adapter.GetOrder(ctx, want.OrderUID)
+				require.NoError(t, err)
+				assert.True(t, want.DateCreated.Equal(got.DateCreated))
+				got.DateCreated = want.DateCreated
+				assert.Equal(t, want, got)
+			}
+
+			// a conflicting batch is rolled back as a whole
+			fresh := newTestOrders(1)[0]
+			assert.Error(t, tt.adapter.SaveOrders(ctx, fresh, orders[0]))
+			_, err := tt.adapter.GetOrder(ctx, fresh.OrderUID)
+			assert.Error(t, err)
+
+			// a redelivered order is rejected even if it was created in another month
+			redelivered := newTestOrders(1)[0]
+			redelivered.OrderUID = orders[0].OrderUID
+			redelivered.DateCreated = orders[0].DateCreated.AddDate(0, -2, 0)
+			assert.Error(t, tt.adapter.SaveOrders(ctx, redelivered))
+
+			// a connection keeps its staging tables, the next batch must not see the last one
+			next := newTestOrders(2)
+			require.NoError(t, tt.adapter.SaveOrders(ctx, next...))
+			var count int
+			require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM orders").Scan(&count))
+			assert.Equal(t, 5, count)
+		})
+	}
+}
+
+func TestPostgresAdapterGetOrder(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestPostgres(t)
+	adapter := NewPostgresAdapter(pool)
+
+	orders := newTestOrders(2)
+	// the lines keep the order they were ingested in, not the one of chrt_id
+	orders[0].Items = append(orders[0].Items, orders[0].Items[0])
+	orders[0].Items[0].ChrtID = orders[0].Items[len(orders[0].Items)-1].ChrtID + 1
+	require.NoError(t, adapter.SaveOrders(ctx, orders...))
+
+	broken := orders[1].OrderUID
+	_, err := pool.Exec(ctx, "DELETE FROM order_items WHERE order_uid = $1", broken)
+	require.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      string
+		want    *models.Order
+		wantErr error
+	}{
+		{name: "found", ctx: ctx, id: orders[0].OrderUID, want: orders[0]},
+		{name: "not found", ctx: ctx, id: "missing", wantErr: errs.ErrOrderNotFound},
+		{name: "without items", ctx: ctx, id: broken, wantErr: errs.ErrOrderItemsNotFound},
+		{name: "query failed", ctx: canceled, id: orders[0].OrderUID, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adapter.GetOrder(tt.ctx, tt.id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, tt.want.DateCreated.Equal(got.DateCreated))
+			got.DateCreated = tt.want.DateCreated
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// BenchmarkPostgresAdapterSaveOrders reports the ingestion rate of both write paths,
+// run it with TEST_POSTGRES_DSN set and -benchtime of a few hundred iterations
+func BenchmarkPostgresAdapterSaveOrders(b *testing.B) {
+	ctx := context.Background()
+	pool := newTestPostgres(b)
+
+	modes := []struct {
+		name    string
+		adapter *PostgresAdapter
+	}{
+		{name: "insert", adapter: NewPostgresAdapter(pool)},
+		{name: "copy", adapter: NewPostgresAdapter(pool).WithCopy(1)},
+	}
+
+	for _, batchSize := range []int{1, 10, 100, 1000} {
+		for _, mode := range modes {
+			b.Run(fmt.Sprintf("batch=%d/%s", batchSize, mode.name), func(b *testing.B) {
+				batches := make([][]*models.Order, b.N)
+				for i := range batches {
+					batches[i] = newTestOrders(batchSize)
+				}
+				b.ResetTimer()
+
+				for i := range b.N {
+					if err := mode.adapter.SaveOrders(ctx, batches[i]...); err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				b.ReportMetric(float64(b.N*batchSize)/b.Elapsed().Seconds(), "orders/s")
+			})
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/jaam8/wb_tech_school_l0/internal/models"
 )
@@ -9,14 +10,68 @@ import (
 type StorageAdapter interface {
 	GetOrder(ctx context.Context, id string) (*models.Order, error)
 	SaveOrders(ctx context.Context, order ...*models.Order) error
+	GetRecentOrderUIDs(ctx context.Context, limit int) ([]string, error)
+	// ForEachOrderUID streams the ids of all stored orders
+	ForEachOrderUID(ctx context.Context, fn func(id string)) error
 }
 
 type BrokerAdapter interface {
 	ConsumeOrderEvent(ctx context.Context) (*models.Order, error)
+	Stats() models.ConsumerStats
 }
 
 type CacheAdapter interface {
 	GetOrder(id string) (*models.Order, error)
+	// GetOrLoadOrder returns the cached order or loads and caches it on a miss,
+	// concurrent misses for one id share a single call of loader
+	GetOrLoadOrder(
+		ctx context.Context, id string, loader func(ctx context.Context) (*models.Order, error),
+	) (*models.Order, models.CacheStatus, error)
 	SaveOrder(key string, val *models.Order) error
+	// HasOrder reports whether the order is cached, expired or not, without counting a hit or a miss
+	HasOrder(key string) bool
+	// RefreshOrder replaces the cached order and drops its not found result without telling
+	// other instances, it is meant for invalidations that every instance receives
+	RefreshOrder(key string, val *models.Order) error
+	// InvalidateNotFound drops the cached not found results of the ids
+	InvalidateNotFound(ids ...string)
+	// InvalidateOrders drops the cached orders and not found results of the ids
+	InvalidateOrders(ids ...string)
 	// SaveOrders(ctx context.Context, orders ...*models.Order) error
 }
+
+// CacheInvalidator broadcasts the ids of changed orders to every instance of the service,
+// so that they drop their local copies
+type CacheInvalidator interface {
+	PublishInvalidation(ctx context.Context, ids ...string) error
+	// SubscribeInvalidations calls fn with the published ids until ctx is done,
+	// an implementation may skip the ids published by its own instance
+	SubscribeInvalidations(ctx context.Context, fn func(ids []string)) error
+}
+
+type CacheAdminAdapter interface {
+	PeekOrder(id string) (*models.CacheEntry, error)
+	// Keys returns up to limit ids of cached orders, all of them if limit is not positive
+	Keys(limit int) []string
+	DeleteOrder(id string) error
+	Purge()
+	Stats() models.CacheStats
+}
+
+type AuditAdapter interface {
+	SaveAuditRecords(ctx context.Context, records ...*models.AuditRecord) error
+	GetAuditRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error)
+}
+
+// PartitionAdapter maintains the monthly partitions of the orders and their items,
+// a month is given by its first moment in UTC
+type PartitionAdapter interface {
+	// ListOrderPartitions returns the months partitions exist for, oldest first
+	ListOrderPartitions(ctx context.Context) ([]time.Time, error)
+	CreateOrderPartition(ctx context.Context, month time.Time) error
+	// ForEachOrderInPartition streams the orders of the partition of the month
+	ForEachOrderInPartition(ctx context.Context, month time.Time, fn func(order *models.Order) error) error
+	// RetireOrderPartition detaches the partitions of the month from the tables,
+	// with drop they are dropped along with the rest of their orders
+	RetireOrderPartition(ctx context.Context, month time.Time, drop bool) error
+}
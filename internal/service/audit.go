@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jaam8/wb_tech_school_l0/internal/ports"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 1000
+	auditShutdownTimeout   = 5 * time.Second
+	auditRetryMinBackoff   = 100 * time.Millisecond
+	auditRetryMaxBackoff   = 5 * time.Second
+
+	// the sizes of the audit_log columns filled from the request,
+	// a longer value would fail the COPY of the whole batch
+	maxAuditCallerLen   = 100
+	maxAuditOrderUIDLen = 60
+)
+
+// Auditor records order reads and admin actions, the records are written to storage in batches by Run.
+// While storage is down Run retries the batch and the buffer fills up, then Record fails
+type Auditor struct {
+	storage       ports.AuditAdapter
+	records       chan *models.AuditRecord
+	batchSize     int
+	flushInterval time.Duration
+	recordTimeout time.Duration
+}
+
+func NewAuditor(
+	storage ports.AuditAdapter,
+	bufferSize, batchSize int,
+	flushInterval time.Duration,
+) *Auditor {
+	return &Auditor{
+		storage:       storage,
+		records:       make(chan *models.AuditRecord, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// WithRecordTimeout sets how long Record waits for room in a full buffer, it does not wait by default
+func (a *Auditor) WithRecordTimeout(timeout time.Duration) *Auditor {
+	a.recordTimeout = timeout
+	return a
+}
+
+// Record queues the record for writing. If the buffer stays full for the record timeout
+// or ctx is done first, the record is dropped and ErrAuditUnavailable is returned.
+// The callers and order id come from the client, they are cut to the size of their columns
+func (a *Auditor) Record(ctx context.Context, record *models.AuditRecord) error {
+	if record.OccurredAt.IsZero() {
+		record.OccurredAt = time.Now().UTC()
+	}
+	record.Caller = truncate(record.Caller, maxAuditCallerLen)
+	record.ClaimedCaller = truncate(record.ClaimedCaller, maxAuditCallerLen)
+	record.OrderUID = truncate(record.OrderUID, maxAuditOrderUIDLen)
+
+	select {
+	case a.records <- record:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(a.recordTimeout)
+	defer timer.Stop()
+	select {
+	case a.records <- record:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	logger.Error(ctx, "audit buffer is full, record dropped",
+		zap.String("action", record.Action),
+		zap.String("caller", record.Caller),
+		zap.String("order_uid", record.OrderUID),
+	)
+
+	return errs.ErrAuditUnavailable
+}
+
+// Run writes queued records until ctx is done, then flushes what is left
+func (a *Auditor) Run(ctx context.Context) {
+	ctx = logger.WithComponent(ctx, "audit")
+
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.AuditRecord, 0, a.batchSize)
+
+	// flush retries the batch until it is saved or ctx is done, an unsaved batch is kept for the next flush
+	flush := func(ctx context.Context) {
+		backoff := auditRetryMinBackoff
+		for len(batch) > 0 {
+			err := a.storage.SaveAuditRecords(ctx, batch...)
+			if err == nil {
+				batch = batch[:0]
+				return
+			}
+			logger.Error(ctx, "failed to save audit records",
+				zap.Int("count", len(batch)),
+				zap.Duration("retry_in", backoff),
+				zap.Error(err),
+			)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, auditRetryMaxBackoff)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditShutdownTimeout)
+			defer cancel()
+			for {
+				select {
+				case record := <-a.records:
+					batch = append(batch, record)
+					if len(batch) >= a.batchSize {
+						flush(shutdownCtx)
+					}
+				default:
+					flush(shutdownCtx)
+					if len(batch) > 0 {
+						logger.Error(ctx, "audit records lost on shutdown", zap.Int("count", len(batch)))
+					}
+					logger.Info(ctx, "stop auditor")
+					return
+				}
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case record := <-a.records:
+			batch = append(batch, record)
+			if len(batch) >= a.batchSize {
+				flush(ctx)
+			}
+		}
+	}
+}
+
+// Query returns the newest records matching the filter
+func (a *Auditor) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditQueryLimit
+	}
+	filter.Limit = min(filter.Limit, maxAuditQueryLimit)
+
+	return a.storage.GetAuditRecords(ctx, filter)
+}
+
+// truncate cuts s to at most n characters, invalid UTF-8 is replaced as Postgres rejects it
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return string([]rune(s)[:n])
+}
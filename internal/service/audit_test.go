@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAuditAdapter struct {
+	mock.Mock
+
+	mu    sync.Mutex
+	saved []*models.AuditRecord
+}
+
+func (m *MockAuditAdapter) SaveAuditRecords(ctx context.Context, records ...*models.AuditRecord) error {
+	args := m.Called(ctx, len(records))
+	if args.Error(0) != nil {
+		return args.Error(0)
+	}
+
+	m.mu.Lock()
+	m.saved = append(m.saved, records...)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MockAuditAdapter) GetAuditRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditRecord), args.Error(1)
+}
+
+func (m *MockAuditAdapter) Saved() []*models.AuditRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saved
+}
+
+func TestAuditor_Run(t *testing.T) {
+	tests := []struct {
+		name          string
+		batchSize     int
+		flushInterval time.Duration
+		records       int
+		mockSetup     func(storage *MockAuditAdapter)
+	}{
+		{
+			name:          "flush full batches",
+			batchSize:     2,
+			flushInterval: time.Minute,
+			records:       4,
+			mockSetup: func(storage *MockAuditAdapter) {
+				storage.On("SaveAuditRecords", mock.Anything, 2).Return(nil).Twice()
+			},
+		},
+		{
+			name:          "flush by interval",
+			batchSize:     10,
+			flushInterval: 10 * time.Millisecond,
+			records:       3,
+			mockSetup: func(storage *MockAuditAdapter) {
+				storage.On("SaveAuditRecords", mock.Anything, 3).Return(nil).Once()
+			},
+		},
+		{
+			name:          "flush rest on shutdown",
+			batchSize:     2,
+			flushInterval: time.Minute,
+			records:       3,
+			mockSetup: func(storage *MockAuditAdapter) {
+				storage.On("SaveAuditRecords", mock.Anything, 2).Return(nil).Once()
+				storage.On("SaveAuditRecords", mock.Anything, 1).Return(nil).Once()
+			},
+		},
+		{
+			name:          "retry failed batch",
+			batchSize:     2,
+			flushInterval: time.Minute,
+			records:       2,
+			mockSetup: func(storage *MockAuditAdapter) {
+				storage.On("SaveAuditRecords", mock.Anything, 2).Return(errors.New("connection refused")).Twice()
+				storage.On("SaveAuditRecords", mock.Anything, 2).Return(nil).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(MockAuditAdapter)
+			tt.mockSetup(storage)
+
+			auditor := NewAuditor(storage, 16, tt.batchSize, tt.flushInterval)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				auditor.Run(ctx)
+			}()
+
+			for range tt.records {
+				require.NoError(t, auditor.Record(ctx, &models.AuditRecord{Action: models.AuditActionOrderRead}))
+			}
+			require.Eventually(t, func() bool {
+				return len(storage.Saved()) >= tt.records-tt.records%tt.batchSize
+			}, time.Second, 5*time.Millisecond)
+
+			cancel()
+			<-done
+
+			require.Len(t, storage.Saved(), tt.records)
+			for _, r := range storage.Saved() {
+				require.False(t, r.OccurredAt.IsZero(), "OccurredAt should be set on Record")
+			}
+			storage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuditor_RecordWhenFull(t *testing.T) {
+	tests := []struct {
+		name          string
+		recordTimeout time.Duration
+		freeAfter     time.Duration
+		wantErr       error
+	}{
+		{
+			name:    "dropped without timeout",
+			wantErr: errs.ErrAuditUnavailable,
+		},
+		{
+			name:          "dropped after timeout",
+			recordTimeout: 20 * time.Millisecond,
+			wantErr:       errs.ErrAuditUnavailable,
+		},
+		{
+			name:          "queued once there is room",
+			recordTimeout: time.Second,
+			freeAfter:     20 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor := NewAuditor(new(MockAuditAdapter), 1, 1, time.Minute).
+				WithRecordTimeout(tt.recordTimeout)
+			require.NoError(t, auditor.Record(context.Background(), &models.AuditRecord{OrderUID: "first"}))
+
+			if tt.freeAfter > 0 {
+				time.AfterFunc(tt.freeAfter, func() { <-auditor.records })
+			}
+			start := time.Now()
+			err := auditor.Record(context.Background(), &models.AuditRecord{OrderUID: "second"})
+
+			require.ErrorIs(t, err, tt.wantErr)
+			require.GreaterOrEqual(t, time.Since(start), min(tt.recordTimeout, tt.freeAfter))
+			require.Len(t, auditor.records, 1)
+			if tt.wantErr == nil {
+				require.Equal(t, "second", (<-auditor.records).OrderUID)
+			}
+		})
+	}
+}
+
+func TestAuditor_RecordTruncates(t *testing.T) {
+	tests := []struct {
+		name         string
+		caller       string
+		orderUID     string
+		wantCaller   string
+		wantOrderUID string
+	}{
+		{
+			name:         "short values are kept",
+			caller:       "admin",
+			orderUID:     "b563feb7b2b84b6test",
+			wantCaller:   "admin",
+			wantOrderUID: "b563feb7b2b84b6test",
+		},
+		{
+			name:         "long values are cut",
+			caller:       strings.Repeat("к", maxAuditCallerLen+1),
+			orderUID:     strings.Repeat("x", 1000),
+			wantCaller:   strings.Repeat("к", maxAuditCallerLen),
+			wantOrderUID: strings.Repeat("x", maxAuditOrderUIDLen),
+		},
+		{
+			name:         "invalid utf-8 is replaced",
+			caller:       "admin\xff",
+			wantCaller:   "admin\uFFFD",
+			wantOrderUID: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor := NewAuditor(new(MockAuditAdapter), 1, 1, time.Minute)
+
+			err := auditor.Record(context.Background(), &models.AuditRecord{
+				Caller:        tt.caller,
+				ClaimedCaller: tt.caller,
+				OrderUID:      tt.orderUID,
+			})
+			require.NoError(t, err)
+			record := <-auditor.records
+			require.Equal(t, tt.wantCaller, record.Caller)
+			require.Equal(t, tt.wantCaller, record.ClaimedCaller)
+			require.Equal(t, tt.wantOrderUID, record.OrderUID)
+		})
+	}
+}
+
+func TestAuditor_QueryLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		wantLimit int
+	}{
+		{name: "default limit", limit: 0, wantLimit: defaultAuditQueryLimit},
+		{name: "custom limit", limit: 10, wantLimit: 10},
+		{name: "capped limit", limit: 100000, wantLimit: maxAuditQueryLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(MockAuditAdapter)
+			storage.On("GetAuditRecords", mock.Anything, models.AuditFilter{Limit: tt.wantLimit}).
+				Return([]models.AuditRecord{}, nil)
+
+			auditor := NewAuditor(storage, 1, 1, time.Minute)
+			_, err := auditor.Query(context.Background(), models.AuditFilter{Limit: tt.limit})
+
+			require.NoError(t, err)
+			storage.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jaam8/wb_tech_school_l0/internal/ports"
+	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PartitionMaintainer creates the monthly partitions of the orders ahead of time
+// and retires the ones that fell out of retention, optionally archiving them first
+type PartitionMaintainer struct {
+	storage  ports.PartitionAdapter
+	interval time.Duration
+	now      func() time.Time
+
+	// ahead is the number of months after the current one partitions are created for
+	ahead int
+	// retention is the number of months before the current one that are kept, zero keeps all
+	retention int
+	drop      bool
+
+	// archiveDir is empty unless retired partitions are exported to gzip compressed JSONL files
+	archiveDir string
+}
+
+func NewPartitionMaintainer(
+	storage ports.PartitionAdapter,
+	ahead, retention int,
+	drop bool,
+	interval time.Duration,
+) *PartitionMaintainer {
+	return &PartitionMaintainer{
+		storage:   storage,
+		interval:  interval,
+		now:       time.Now,
+		ahead:     ahead,
+		retention: retention,
+		drop:      drop,
+	}
+}
+
+// WithArchive makes the maintainer export every order of a partition to dir before retiring it,
+// a partition that fails to export is kept. It must be called before the maintainer is used
+func (m *PartitionMaintainer) WithArchive(dir string) *PartitionMaintainer {
+	m.archiveDir = dir
+	return m
+}
+
+// Run maintains the partitions right away and then every interval until ctx is done
+func (m *PartitionMaintainer) Run(ctx context.Context) {
+	ctx = logger.WithComponent(ctx, "partitions")
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Maintain(ctx); err != nil {
+			logger.Error(ctx, "failed to maintain partitions", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info(ctx, "stop partition maintainer")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Maintain creates the partitions of the current month and of the ahead next ones,
+// then retires the partitions older than retention months, oldest first.
+// A month that fails to be created does not keep the others from being maintained
+func (m *PartitionMaintainer) Maintain(ctx context.Context) error {
+	now := m.now().UTC()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var createErrs []error
+	for i := range m.ahead + 1 {
+		month := current.AddDate(0, i, 0)
+		if err := m.storage.CreateOrderPartition(ctx, month); err != nil {
+			createErrs = append(createErrs,
+				fmt.Errorf("failed to create partition of %s: %w", month.Format("2006-01"), err))
+		}
+	}
+
+	return errors.Join(append(createErrs, m.retire(ctx, current))...)
+}
+
+// retire retires the partitions older than retention months, it stops at the first failure
+func (m *PartitionMaintainer) retire(ctx context.Context, current time.Time) error {
+	if m.retention <= 0 {
+		return nil
+	}
+
+	months, err := m.storage.ListOrderPartitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	cutoff := current.AddDate(0, -m.retention, 0)
+	for _, month := range months {
+		if !month.Before(cutoff) {
+			break
+		}
+
+		if m.archiveDir != "" {
+			if err = m.archive(ctx, month); err != nil {
+				return fmt.Errorf("failed to archive partition of %s: %w", month.Format("2006-01"), err)
+			}
+		}
+		if err = m.storage.RetireOrderPartition(ctx, month, m.drop); err != nil {
+			return fmt.Errorf("failed to retire partition of %s: %w", month.Format("2006-01"), err)
+		}
+		logger.Info(ctx, "retired partition",
+			zap.String("month", month.Format("2006-01")),
+			zap.Bool("dropped", m.drop),
+		)
+	}
+
+	return nil
+}
+
+// archive writes the orders of the month to orders_YYYY_MM.jsonl.gz in the archive dir,
+// one order per line. The file appears only once it is complete
+func (m *PartitionMaintainer) archive(ctx context.Context, month time.Time) error {
+	if err := os.MkdirAll(m.archiveDir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(m.archiveDir, ".orders_*.jsonl.gz")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// a no-op once the file has been renamed
+		_ = os.Remove(file.Name())
+	}()
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	count := 0
+	err = m.storage.ForEachOrderInPartition(ctx, month, func(order *models.Order) error {
+		count++
+		return encoder.Encode(order)
+	})
+	if err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	path := filepath.Join(m.archiveDir, "orders_"+month.Format("2006_01")+".jsonl.gz")
+	if err = os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+	logger.Info(ctx, "archived partition",
+		zap.String("path", path),
+		zap.Int("orders", count),
+	)
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPartitionAdapter struct {
+	mock.Mock
+}
+
+func (m *MockPartitionAdapter) ListOrderPartitions(ctx context.Context) ([]time.Time, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]time.Time), args.Error(1)
+}
+
+func (m *MockPartitionAdapter) CreateOrderPartition(ctx context.Context, month time.Time) error {
+	args := m.Called(ctx, month)
+	return args.Error(0)
+}
+
+func (m *MockPartitionAdapter) ForEachOrderInPartition(
+	ctx context.Context, month time.Time, fn func(order *models.Order) error,
+) error {
+	args := m.Called(ctx, month)
+	if orders, ok := args.Get(0).([]*models.Order); ok {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockPartitionAdapter) RetireOrderPartition(ctx context.Context, month time.Time, drop bool) error {
+	args := m.Called(ctx, month, drop)
+	return args.Error(0)
+}
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPartitionMaintainer_Maintain(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	existing := []time.Time{month(2025, 12), month(2026, 1), month(2026, 2), month(2026, 3)}
+	failing := errors.New("database is down")
+
+	expectCreated := func(storage *MockPartitionAdapter) {
+		for _, m := range []time.Time{month(2026, 3), month(2026, 4), month(2026, 5)} {
+			storage.On("CreateOrderPartition", mock.Anything, m).Return(nil).Once()
+		}
+	}
+
+	tests := []struct {
+		name      string
+		retention int
+		drop      bool
+		mockSetup func(storage *MockPartitionAdapter)
+		wantErr   error
+	}{
+		{
+			name:      "keep every partition",
+			retention: 0,
+			mockSetup: expectCreated,
+		},
+		{
+			name:      "drop partitions out of retention",
+			retention: 2,
+			drop:      true,
+			mockSetup: func(storage *MockPartitionAdapter) {
+				expectCreated(storage)
+				storage.On("ListOrderPartitions", mock.Anything).Return(existing, nil).Once()
+				storage.On("RetireOrderPartition", mock.Anything, month(2025, 12), true).Return(nil).Once()
+			},
+		},
+		{
+			name:      "detach partitions out of retention",
+			retention: 1,
+			mockSetup: func(storage *MockPartitionAdapter) {
+				expectCreated(storage)
+				storage.On("ListOrderPartitions", mock.Anything).Return(existing, nil).Once()
+				storage.On("RetireOrderPartition", mock.Anything, month(2025, 12), false).Return(nil).Once()
+				storage.On("RetireOrderPartition", mock.Anything, month(2026, 1), false).Return(nil).Once()
+			},
+		},
+		{
+			name:      "stop at the first failed retirement",
+			retention: 1,
+			drop:      true,
+			mockSetup: func(storage *MockPartitionAdapter) {
+				expectCreated(storage)
+				storage.On("ListOrderPartitions", mock.Anything).Return(existing, nil).Once()
+				storage.On("RetireOrderPartition", mock.Anything, month(2025, 12), true).Return(failing).Once()
+			},
+			wantErr: failing,
+		},
+		{
+			name:      "failed creation does not stop maintenance",
+			retention: 1,
+			mockSetup: func(storage *MockPartitionAdapter) {
+				storage.On("CreateOrderPartition", mock.Anything, month(2026, 3)).Return(failing).Once()
+				storage.On("CreateOrderPartition", mock.Anything, month(2026, 4)).Return(nil).Once()
+				storage.On("CreateOrderPartition", mock.Anything, month(2026, 5)).Return(nil).Once()
+				storage.On("ListOrderPartitions", mock.Anything).Return(existing, nil).Once()
+				storage.On("RetireOrderPartition", mock.Anything, month(2025, 12), false).Return(nil).Once()
+				storage.On("RetireOrderPartition", mock.Anything, month(2026, 1), false).Return(nil).Once()
+			},
+			wantErr: failing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(MockPartitionAdapter)
+			tt.mockSetup(storage)
+
+			maintainer := NewPartitionMaintainer(storage, 2, tt.retention, tt.drop, time.Hour)
+			maintainer.now = func() time.Time { return now }
+
+			err := maintainer.Maintain(context.Background())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			storage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPartitionMaintainer_Archive(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "archive")
+	old := month(2025, 1)
+	orders := []*models.Order{{OrderUID: "first"}, {OrderUID: "second"}}
+	failing := errors.New("connection reset")
+
+	storage := new(MockPartitionAdapter)
+	storage.On("CreateOrderPartition", mock.Anything, mock.Anything).Return(nil)
+	storage.On("ListOrderPartitions", mock.Anything).Return([]time.Time{old}, nil)
+	// the first export breaks off, the partition must survive it
+	storage.On("ForEachOrderInPartition", mock.Anything, old).Return(orders[:1], failing).Once()
+	storage.On("ForEachOrderInPartition", mock.Anything, old).Return(orders, nil).Once()
+	storage.On("RetireOrderPartition", mock.Anything, old, true).Return(nil).Once()
+
+	maintainer := NewPartitionMaintainer(storage, 0, 1, true, time.Hour).WithArchive(dir)
+	maintainer.now = func() time.Time { return month(2026, 3) }
+
+	assert.ErrorIs(t, maintainer.Maintain(ctx), failing)
+	storage.AssertNotCalled(t, "RetireOrderPartition", mock.Anything, old, true)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	require.NoError(t, maintainer.Maintain(ctx))
+	storage.AssertExpectations(t)
+
+	file, err := os.Open(filepath.Join(dir, "orders_2025_01.jsonl.gz"))
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	decoder := json.NewDecoder(gz)
+	var ids []string
+	for decoder.More() {
+		var order models.Order
+		require.NoError(t, decoder.Decode(&order))
+		ids = append(ids, order.OrderUID)
+	}
+	assert.Equal(t, []string{"first", "second"}, ids)
+}
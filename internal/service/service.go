@@ -2,20 +2,36 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/jaam8/wb_tech_school_l0/internal/models"
 	"github.com/jaam8/wb_tech_school_l0/internal/ports"
+	"github.com/jaam8/wb_tech_school_l0/pkg/bloom"
 	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
+	"github.com/jaam8/wb_tech_school_l0/pkg/health"
 	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
 	"go.uber.org/zap"
 )
 
 type Service struct {
-	cache   ports.CacheAdapter
-	broker  ports.BrokerAdapter
-	storage ports.StorageAdapter
+	cache     ports.CacheAdapter
+	broker    ports.BrokerAdapter
+	storage   ports.StorageAdapter
+	heartbeat *health.Heartbeat
+	inFlight  atomic.Int64
+
+	// known holds the ids of stored orders, it is consulted only after LoadKnownOrders
+	// has filled it. A missing id may still be in the storage, stored by another instance
+	// whose invalidation has not arrived or was lost, so it is never rejected by the filter alone
+	known      *bloom.Filter
+	knownReady atomic.Bool
+
+	// invalidator is nil unless the ids of stored orders are broadcast to all instances
+	invalidator ports.CacheInvalidator
+	refresh     bool
 }
 
 func New(
@@ -24,13 +40,125 @@ func New(
 	storage ports.StorageAdapter,
 ) *Service {
 	return &Service{
-		cache:   cache,
-		broker:  broker,
-		storage: storage,
+		cache:     cache,
+		broker:    broker,
+		storage:   storage,
+		heartbeat: health.NewHeartbeat(),
+	}
+}
+
+// UseKnownOrdersFilter makes the service track the ids of stored orders in the filter.
+// GetOrder still looks up the ids missing from it through the cache, whose not found results
+// keep repeated lookups of unknown ids off the storage. It must be called before the service is used
+func (s *Service) UseKnownOrdersFilter(filter *bloom.Filter) {
+	s.known = filter
+}
+
+// UseInvalidator makes the service publish the ids of the orders it stores,
+// so that every instance listening with HandleInvalidations drops them from its cache,
+// or reloads them from the storage if refresh is set. It must be called before the service is used
+func (s *Service) UseInvalidator(invalidator ports.CacheInvalidator, refresh bool) {
+	s.invalidator = invalidator
+	s.refresh = refresh
+}
+
+// HandleInvalidations applies the invalidations published by all instances,
+// including this one, to the cache until ctx is done
+func (s *Service) HandleInvalidations(ctx context.Context) error {
+	if s.invalidator == nil {
+		return nil
+	}
+	ctx = logger.WithComponent(ctx, "invalidation")
+
+	err := s.invalidator.SubscribeInvalidations(ctx, func(ids []string) {
+		// the ids of orders stored by other instances reach the known orders filter only here
+		if s.known != nil {
+			for _, id := range ids {
+				s.known.Add(id)
+			}
+		}
+		if !s.refresh {
+			s.cache.InvalidateOrders(ids...)
+			logger.Debug(ctx, "invalidated cached orders", zap.Strings("order_uids", ids))
+			return
+		}
+		for _, id := range ids {
+			s.refreshOrder(ctx, id)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to handle invalidations: %w", err)
+	}
+
+	return nil
+}
+
+// refreshOrder replaces the cached order with the stored one, or drops it if it cannot be loaded.
+// Orders this instance has not cached are only dropped from the not found results,
+// loading them would cost every instance a storage query per stored order
+func (s *Service) refreshOrder(ctx context.Context, id string) {
+	if !s.cache.HasOrder(id) {
+		s.cache.InvalidateOrders(id)
+		return
+	}
+
+	order, err := s.storage.GetOrder(ctx, id)
+	if err != nil {
+		if !errors.Is(err, errs.ErrOrderNotFound) {
+			logger.Warn(ctx, "failed to refresh cached order",
+				zap.String("order_uid", id),
+				zap.Error(err),
+			)
+		}
+		s.cache.InvalidateOrders(id)
+		return
+	}
+
+	if err = s.cache.RefreshOrder(id, order); err != nil {
+		logger.Warn(ctx, "failed to save order to cache",
+			zap.String("order_uid", id),
+			zap.Error(err),
+		)
+		s.cache.InvalidateOrders(id)
+	}
+}
+
+// LoadKnownOrders adds the ids of all stored orders to the known orders filter and enables it
+func (s *Service) LoadKnownOrders(ctx context.Context) error {
+	if s.known == nil {
+		return nil
+	}
+
+	count := 0
+	err := s.storage.ForEachOrderUID(ctx, func(id string) {
+		s.known.Add(id)
+		count++
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load known orders: %w", err)
+	}
+	s.knownReady.Store(true)
+	logger.Info(ctx, "known orders filter loaded", zap.Int("count", count))
+
+	return nil
+}
+
+// ConsumerHeartbeat is beaten on every iteration of the HandleOrdersEvents loop
+func (s *Service) ConsumerHeartbeat() *health.Heartbeat {
+	return s.heartbeat
+}
+
+// ConsumerState reports the broker offsets and the size of the batch not yet saved to storage
+func (s *Service) ConsumerState() models.ConsumerState {
+	return models.ConsumerState{
+		Broker:        s.broker.Stats(),
+		InFlightBatch: int(s.inFlight.Load()),
+		LastHeartbeat: s.heartbeat.Last(),
 	}
 }
 
 func (s *Service) HandleOrdersEvents(ctx context.Context, batchSize int, flushTimeout time.Duration) {
+	ctx = logger.WithComponent(ctx, "consumer")
 	ticker := time.NewTicker(flushTimeout)
 	defer ticker.Stop()
 
@@ -44,12 +172,18 @@ func (s *Service) HandleOrdersEvents(ctx context.Context, batchSize int, flushTi
 			logger.Error(ctx, "failed to save orders batch to storage",
 				zap.Error(err),
 			)
+		} else {
+			s.markKnown(batch)
+			s.publishInvalidation(ctx, batch)
 		}
 		logger.Info(ctx, "saved orders batch to storage", zap.Int("count", len(batch)))
 		batch = nil
+		s.inFlight.Store(0)
 	}
 
 	for {
+		s.heartbeat.Beat()
+
 		select {
 		case <-ctx.Done():
 			flushBatch()
@@ -58,8 +192,14 @@ func (s *Service) HandleOrdersEvents(ctx context.Context, batchSize int, flushTi
 		case <-ticker.C:
 			flushBatch()
 		default:
-			event, err := s.broker.ConsumeOrderEvent(ctx)
+			// bound the read so an idle topic neither blocks the flush ticker nor stops the heartbeat
+			readCtx, cancel := context.WithTimeout(ctx, flushTimeout)
+			event, err := s.broker.ConsumeOrderEvent(readCtx)
+			cancel()
 			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+					continue
+				}
 				logger.Error(ctx, "failed to consume order event",
 					zap.Error(err),
 				)
@@ -78,6 +218,7 @@ func (s *Service) HandleOrdersEvents(ctx context.Context, batchSize int, flushTi
 			}
 
 			batch = append(batch, event)
+			s.inFlight.Store(int64(len(batch)))
 
 			if len(batch) >= batchSize {
 				flushBatch()
@@ -86,31 +227,107 @@ func (s *Service) HandleOrdersEvents(ctx context.Context, batchSize int, flushTi
 	}
 }
 
-func (s *Service) GetOrder(ctx context.Context, id string) (*models.Order, error) {
-	logger.With(ctx,
+// markKnown makes stored orders visible to GetOrder
+// past the known orders filter and the cached not found results
+func (s *Service) markKnown(orders []*models.Order) {
+	ids := make([]string, len(orders))
+	for i, order := range orders {
+		ids[i] = order.OrderUID
+		if s.known != nil {
+			s.known.Add(order.OrderUID)
+		}
+	}
+	s.cache.InvalidateNotFound(ids...)
+}
+
+// publishInvalidation broadcasts the ids of stored orders, the orders are already in the storage,
+// so a failure only leaves the caches of other instances stale until their TTL.
+// Their known orders filters miss the ids, which are looked up all the same, see GetOrder
+func (s *Service) publishInvalidation(ctx context.Context, orders []*models.Order) {
+	if s.invalidator == nil {
+		return
+	}
+
+	ids := make([]string, len(orders))
+	for i, order := range orders {
+		ids[i] = order.OrderUID
+	}
+	if err := s.invalidator.PublishInvalidation(ctx, ids...); err != nil {
+		logger.Warn(ctx, "failed to publish cache invalidation",
+			zap.Int("count", len(ids)),
+			zap.Error(err),
+		)
+	}
+}
+
+// WarmUpCache loads up to limit most recent orders from storage into the cache
+func (s *Service) WarmUpCache(ctx context.Context, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	ids, err := s.storage.GetRecentOrderUIDs(ctx, limit)
+	if err != nil {
+		return fmt.Errorf("failed to get recent orders: %w", err)
+	}
+
+	for _, id := range ids {
+		order, err := s.storage.GetOrder(ctx, id)
+		if err != nil {
+			logger.Warn(ctx, "failed to get order for cache warm-up",
+				zap.String("order_uid", id),
+				zap.Error(err),
+			)
+			continue
+		}
+		if err = s.cache.SaveOrder(id, order); err != nil {
+			logger.Warn(ctx, "failed to save order to cache",
+				zap.String("order_uid", id),
+				zap.Error(err),
+			)
+		}
+	}
+	logger.Info(ctx, "cache warmed up", zap.Int("count", len(ids)))
+
+	return nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, id string) (*models.Order, models.CacheStatus, error) {
+	ctx = logger.With(ctx,
 		zap.String("order_uid", id),
 	)
 
 	if id == "" {
-		return nil, errs.ErrEmptyOrderUID
+		return nil, models.CacheMiss, errs.ErrEmptyOrderUID
 	}
 	logger.Info(ctx, "get order")
 
-	order, err := s.cache.GetOrder(id)
+	// the filter may miss orders stored by other instances, so a miss is still looked up
+	unknown := s.known != nil && s.knownReady.Load() && !s.known.Test(id)
+	if unknown {
+		logger.Debug(ctx, "order not in known orders filter")
+	}
+
+	// concurrent misses for one id share a single storage query
+	order, status, err := s.cache.GetOrLoadOrder(ctx, id, func(ctx context.Context) (*models.Order, error) {
+		logger.Info(ctx, "order not in cache, loading from storage")
+		return s.storage.GetOrder(ctx, id)
+	})
+	if errors.Is(err, errs.ErrOrderNotFound) {
+		logger.Info(ctx, "order not found", zap.String("cache", string(status)))
+		return nil, status, fmt.Errorf("failed to get order: %w", err)
+	}
 	if err != nil {
-		logger.Warn(ctx, "failed to get order from cache", zap.Error(err))
+		logger.Error(ctx, "failed to get order from storage", zap.Error(err))
+		return nil, status, fmt.Errorf("failed to get order: %w", err)
+	}
 
-		order, err = s.storage.GetOrder(ctx, id)
-		if err != nil {
-			logger.Error(ctx, "failed to get order from storage", zap.Error(err))
-			return nil, fmt.Errorf("failed to get order: %w", err)
-		}
-		err = s.cache.SaveOrder(id, order)
-		if err != nil {
-			logger.Error(ctx, "failed to save order to cache", zap.Error(err))
-		}
+	if unknown {
+		// stored by another instance before this one subscribed to the invalidations,
+		// or its invalidation was lost
+		s.known.Add(id)
 	}
 
-	logger.Info(ctx, "got order")
-	return order, nil
+	logger.Info(ctx, "got order", zap.String("cache", string(status)))
+	return order, status, nil
 }
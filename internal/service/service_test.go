@@ -3,12 +3,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jaam8/wb_tech_school_l0/internal/models"
+	"github.com/jaam8/wb_tech_school_l0/internal/ports/adapters/cache"
+	"github.com/jaam8/wb_tech_school_l0/pkg/bloom"
 	errs "github.com/jaam8/wb_tech_school_l0/pkg/errors"
-	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
+	lrucache "github.com/jaam8/wb_tech_school_l0/pkg/lru-cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -26,6 +29,41 @@ func (m *MockCacheAdapter) GetOrder(key string) (*models.Order, error) {
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
+// GetOrLoadOrder follows the contract of the real adapter on top of the GetOrder and SaveOrder mocks
+func (m *MockCacheAdapter) GetOrLoadOrder(
+	ctx context.Context, id string, loader func(ctx context.Context) (*models.Order, error),
+) (*models.Order, models.CacheStatus, error) {
+	if order, err := m.GetOrder(id); err == nil {
+		return order, models.CacheHit, nil
+	}
+
+	order, err := loader(ctx)
+	if err != nil {
+		return nil, models.CacheMiss, err
+	}
+	_ = m.SaveOrder(id, order)
+
+	return order, models.CacheMiss, nil
+}
+
+func (m *MockCacheAdapter) HasOrder(key string) bool {
+	args := m.Called(key)
+	return args.Bool(0)
+}
+
+func (m *MockCacheAdapter) RefreshOrder(key string, val *models.Order) error {
+	args := m.Called(key, val)
+	return args.Error(0)
+}
+
+func (m *MockCacheAdapter) InvalidateNotFound(ids ...string) {
+	m.Called(ids)
+}
+
+func (m *MockCacheAdapter) InvalidateOrders(ids ...string) {
+	m.Called(ids)
+}
+
 func (m *MockCacheAdapter) SaveOrder(key string, val *models.Order) error {
 	args := m.Called(key, val)
 	return args.Error(0)
@@ -48,6 +86,24 @@ func (m *MockStorageAdapter) SaveOrders(ctx context.Context, order ...*models.Or
 	return args.Error(0)
 }
 
+func (m *MockStorageAdapter) GetRecentOrderUIDs(ctx context.Context, limit int) ([]string, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStorageAdapter) ForEachOrderUID(ctx context.Context, fn func(id string)) error {
+	args := m.Called(ctx)
+	if ids, ok := args.Get(0).([]string); ok {
+		for _, id := range ids {
+			fn(id)
+		}
+	}
+	return args.Error(1)
+}
+
 type MockBrokerAdapter struct {
 	mock.Mock
 }
@@ -60,13 +116,36 @@ func (m *MockBrokerAdapter) ConsumeOrderEvent(ctx context.Context) (*models.Orde
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
+func (m *MockBrokerAdapter) Stats() models.ConsumerStats {
+	args := m.Called()
+	return args.Get(0).(models.ConsumerStats)
+}
+
+type MockCacheInvalidator struct {
+	mock.Mock
+}
+
+func (m *MockCacheInvalidator) PublishInvalidation(ctx context.Context, ids ...string) error {
+	args := m.Called(ctx, ids)
+	return args.Error(0)
+}
+
+func (m *MockCacheInvalidator) SubscribeInvalidations(ctx context.Context, fn func(ids []string)) error {
+	args := m.Called(ctx)
+	if ids, ok := args.Get(0).([]string); ok {
+		fn(ids)
+	}
+	return args.Error(1)
+}
+
 func TestService_GetOrder(t *testing.T) {
 	tests := []struct {
-		name      string
-		id        string
-		want      *models.Order
-		wantErr   error
-		mockSetup func(storage *MockStorageAdapter, cache *MockCacheAdapter)
+		name       string
+		id         string
+		want       *models.Order
+		wantStatus models.CacheStatus
+		wantErr    error
+		mockSetup  func(storage *MockStorageAdapter, cache *MockCacheAdapter)
 	}{
 		{
 			name: "success got order from cache",
@@ -74,7 +153,8 @@ func TestService_GetOrder(t *testing.T) {
 			want: &models.Order{
 				OrderUID: "test_order_uid",
 			},
-			wantErr: nil,
+			wantStatus: models.CacheHit,
+			wantErr:    nil,
 			mockSetup: func(storage *MockStorageAdapter, cache *MockCacheAdapter) {
 				cache.On("GetOrder", "test_order_uid").
 					Return(&models.Order{
@@ -88,7 +168,8 @@ func TestService_GetOrder(t *testing.T) {
 			want: &models.Order{
 				OrderUID: "test_order_uid",
 			},
-			wantErr: nil,
+			wantStatus: models.CacheMiss,
+			wantErr:    nil,
 			mockSetup: func(storage *MockStorageAdapter, cache *MockCacheAdapter) {
 				cache.On("GetOrder", "test_order_uid").
 					Return(nil, errs.ErrOrderNotFound)
@@ -131,8 +212,7 @@ func TestService_GetOrder(t *testing.T) {
 			service := New(cache, nil, storage)
 
 			ctx := context.Background()
-			ctx, _ = logger.New(ctx)
-			order, err := service.GetOrder(ctx, tt.id)
+			order, status, err := service.GetOrder(ctx, tt.id)
 
 			if tt.wantErr != nil {
 				require.Error(t, err)
@@ -141,6 +221,67 @@ func TestService_GetOrder(t *testing.T) {
 				require.NoError(t, err)
 				assert.NotNil(t, order)
 				assert.Equal(t, "test_order_uid", order.OrderUID)
+				assert.Equal(t, tt.wantStatus, status)
+			}
+
+			cache.AssertExpectations(t)
+			storage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_WarmUpCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		wantErr   bool
+		mockSetup func(storage *MockStorageAdapter, cache *MockCacheAdapter)
+	}{
+		{
+			name:  "warm up recent orders",
+			limit: 2,
+			mockSetup: func(storage *MockStorageAdapter, cache *MockCacheAdapter) {
+				storage.On("GetRecentOrderUIDs", mock.Anything, 2).
+					Return([]string{"first", "second"}, nil)
+				storage.On("GetOrder", mock.Anything, "first").
+					Return(&models.Order{OrderUID: "first"}, nil)
+				storage.On("GetOrder", mock.Anything, "second").
+					Return(nil, errs.ErrOrderNotFound)
+				cache.On("SaveOrder", "first", &models.Order{OrderUID: "first"}).
+					Return(nil)
+			},
+		},
+		{
+			name:    "storage error",
+			limit:   2,
+			wantErr: true,
+			mockSetup: func(storage *MockStorageAdapter, cache *MockCacheAdapter) {
+				storage.On("GetRecentOrderUIDs", mock.Anything, 2).
+					Return(nil, fmt.Errorf("connection refused"))
+			},
+		},
+		{
+			name:  "disabled",
+			limit: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(MockStorageAdapter)
+			cache := new(MockCacheAdapter)
+			if tt.mockSetup != nil {
+				tt.mockSetup(storage, cache)
+			}
+
+			service := New(cache, nil, storage)
+
+			err := service.WarmUpCache(context.Background(), tt.limit)
+
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
 			}
 
 			cache.AssertExpectations(t)
@@ -166,7 +307,7 @@ func TestService_HandleOrdersEvents(t *testing.T) {
 		flushTime time.Duration
 		timeout   time.Duration
 		events    []*models.Order
-		mockSetup func(storage *MockStorageAdapter, broker *MockBrokerAdapter)
+		mockSetup func(storage *MockStorageAdapter, broker *MockBrokerAdapter, cache *MockCacheAdapter)
 	}{
 		{
 			name:      "success save orders batch",
@@ -174,7 +315,7 @@ func TestService_HandleOrdersEvents(t *testing.T) {
 			flushTime: time.Millisecond * 100,
 			timeout:   time.Millisecond * 150,
 			events:    orders,
-			mockSetup: func(storage *MockStorageAdapter, broker *MockBrokerAdapter) {
+			mockSetup: func(storage *MockStorageAdapter, broker *MockBrokerAdapter, cache *MockCacheAdapter) {
 				broker.On("ConsumeOrderEvent", mock.Anything).
 					Return(orders[0], nil).Once()
 				broker.On("ConsumeOrderEvent", mock.Anything).
@@ -191,6 +332,7 @@ func TestService_HandleOrdersEvents(t *testing.T) {
 					Return(nil, fmt.Errorf("no more events"))
 
 				storage.On("SaveOrders", mock.Anything, orders).Return(nil).Once()
+				cache.On("InvalidateNotFound", []string{orders[0].OrderUID, orders[1].OrderUID}).Once()
 			},
 		},
 		{
@@ -199,7 +341,7 @@ func TestService_HandleOrdersEvents(t *testing.T) {
 			flushTime: time.Millisecond * 50,
 			timeout:   time.Millisecond * 100,
 			events:    orders[:1],
-			mockSetup: func(storage *MockStorageAdapter, broker *MockBrokerAdapter) {
+			mockSetup: func(storage *MockStorageAdapter, broker *MockBrokerAdapter, cache *MockCacheAdapter) {
 				broker.On("ConsumeOrderEvent", mock.Anything).
 					Return(orders[0], nil).Once()
 				broker.On("ConsumeOrderEvent", mock.Anything).
@@ -214,6 +356,31 @@ func TestService_HandleOrdersEvents(t *testing.T) {
 					Return(nil, fmt.Errorf("no more events"))
 
 				storage.On("SaveOrders", mock.Anything, []*models.Order{orders[0]}).Return(nil).Once()
+				cache.On("InvalidateNotFound", []string{orders[0].OrderUID}).Once()
+			},
+		},
+		{
+			name:      "failed save keeps not found results",
+			batchSize: 1,
+			flushTime: time.Millisecond * 50,
+			timeout:   time.Millisecond * 100,
+			events:    orders[:1],
+			mockSetup: func(storage *MockStorageAdapter, broker *MockBrokerAdapter, cache *MockCacheAdapter) {
+				broker.On("ConsumeOrderEvent", mock.Anything).
+					Return(orders[0], nil).Once()
+				broker.On("ConsumeOrderEvent", mock.Anything).
+					Run(func(args mock.Arguments) {
+						ctx := args.Get(0).(context.Context)
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(time.Millisecond * 10):
+						}
+					}).
+					Return(nil, fmt.Errorf("no more events"))
+
+				storage.On("SaveOrders", mock.Anything, []*models.Order{orders[0]}).
+					Return(fmt.Errorf("connection refused")).Once()
 			},
 		},
 	}
@@ -222,15 +389,15 @@ func TestService_HandleOrdersEvents(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := new(MockStorageAdapter)
 			broker := new(MockBrokerAdapter)
+			cache := new(MockCacheAdapter)
 			if tt.mockSetup != nil {
-				tt.mockSetup(storage, broker)
+				tt.mockSetup(storage, broker, cache)
 			}
 
-			service := New(nil, broker, storage)
+			service := New(cache, broker, storage)
 
 			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
 			defer cancel()
-			ctx, _ = logger.New(ctx)
 
 			done := make(chan struct{})
 			go func() {
@@ -242,6 +409,211 @@ func TestService_HandleOrdersEvents(t *testing.T) {
 
 			broker.AssertExpectations(t)
 			storage.AssertExpectations(t)
+			cache.AssertExpectations(t)
 		})
 	}
 }
+
+func TestService_GetOrderConcurrentMisses(t *testing.T) {
+	const callers = 20
+
+	storage := new(MockStorageAdapter)
+	storage.On("GetOrder", mock.Anything, "test_order_uid").
+		After(50*time.Millisecond).
+		Return(&models.Order{OrderUID: "test_order_uid"}, nil).
+		Once()
+
+	client := lrucache.NewInMemoryCache[string, *models.Order](10, time.Minute)
+	service := New(cache.NewInMemoryCacheAdapter(client, nil), nil, storage)
+
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, status, err := service.GetOrder(context.Background(), "test_order_uid")
+			assert.NoError(t, err)
+			assert.Equal(t, "test_order_uid", order.OrderUID)
+			assert.Equal(t, models.CacheMiss, status)
+		}()
+	}
+	wg.Wait()
+
+	storage.AssertNumberOfCalls(t, "GetOrder", 1)
+
+	_, status, err := service.GetOrder(context.Background(), "test_order_uid")
+	require.NoError(t, err)
+	require.Equal(t, models.CacheHit, status)
+}
+
+func TestService_GetOrderNotFound(t *testing.T) {
+	storage := new(MockStorageAdapter)
+	storage.On("GetOrder", mock.Anything, "missing").
+		Return(nil, errs.ErrOrderNotFound).
+		Twice()
+
+	adapter := cache.NewInMemoryCacheAdapter(lrucache.NewInMemoryCache[string, *models.Order](10, time.Minute), nil).
+		WithNotFoundCache(lrucache.NewInMemoryCache[string, struct{}](10, time.Minute), time.Minute)
+	service := New(adapter, nil, storage)
+
+	_, status, err := service.GetOrder(context.Background(), "missing")
+	require.ErrorIs(t, err, errs.ErrOrderNotFound)
+	require.Equal(t, models.CacheMiss, status)
+
+	_, status, err = service.GetOrder(context.Background(), "missing")
+	require.ErrorIs(t, err, errs.ErrOrderNotFound)
+	require.Equal(t, models.CacheNegative, status, "not found result should be cached")
+	storage.AssertNumberOfCalls(t, "GetOrder", 1)
+
+	service.markKnown([]*models.Order{{OrderUID: "missing"}})
+	_, status, err = service.GetOrder(context.Background(), "missing")
+	require.ErrorIs(t, err, errs.ErrOrderNotFound)
+	require.Equal(t, models.CacheMiss, status, "ingested id should reach the storage again")
+	storage.AssertNumberOfCalls(t, "GetOrder", 2)
+}
+
+func TestService_KnownOrdersFilter(t *testing.T) {
+	storage := new(MockStorageAdapter)
+	storage.On("ForEachOrderUID", mock.Anything).Return([]string{"known"}, nil)
+	storage.On("GetOrder", mock.Anything, "known").Return(&models.Order{OrderUID: "known"}, nil).Once()
+	storage.On("GetOrder", mock.Anything, "unknown").Return(nil, errs.ErrOrderNotFound).Once()
+	storage.On("GetOrder", mock.Anything, "remote").Return(&models.Order{OrderUID: "remote"}, nil).Once()
+	cacheAdapter := new(MockCacheAdapter)
+	cacheAdapter.On("GetOrder", mock.Anything).Return(nil, errs.ErrOrderNotFound)
+	cacheAdapter.On("SaveOrder", mock.Anything, mock.Anything).Return(nil)
+
+	service := New(cacheAdapter, nil, storage)
+	service.UseKnownOrdersFilter(bloom.New(100, 0.01))
+	service.UseInvalidator(new(MockCacheInvalidator), false)
+	require.NoError(t, service.LoadKnownOrders(context.Background()))
+
+	order, _, err := service.GetOrder(context.Background(), "known")
+	require.NoError(t, err)
+	require.Equal(t, "known", order.OrderUID)
+
+	// ids missing from the filter are looked up all the same
+	_, status, err := service.GetOrder(context.Background(), "unknown")
+	require.ErrorIs(t, err, errs.ErrOrderNotFound)
+	require.Equal(t, models.CacheMiss, status)
+	require.False(t, service.known.Test("unknown"))
+
+	// stored by another instance, the filter never heard of it
+	order, _, err = service.GetOrder(context.Background(), "remote")
+	require.NoError(t, err)
+	require.Equal(t, "remote", order.OrderUID)
+	require.True(t, service.known.Test("remote"), "found id should be added to the filter")
+
+	storage.AssertExpectations(t)
+}
+
+func TestService_PublishInvalidation(t *testing.T) {
+	orders := make([]*models.Order, 0, 2)
+	for len(orders) < 2 {
+		order := models.GenerateFakeOrder()
+		if err := order.Validate(); err != nil {
+			continue
+		}
+		orders = append(orders, &order)
+	}
+	ids := []string{orders[0].OrderUID, orders[1].OrderUID}
+
+	broker := new(MockBrokerAdapter)
+	broker.On("ConsumeOrderEvent", mock.Anything).Return(orders[0], nil).Once()
+	broker.On("ConsumeOrderEvent", mock.Anything).Return(orders[1], nil).Once()
+	broker.On("ConsumeOrderEvent", mock.Anything).
+		Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(nil, context.Canceled)
+	storage := new(MockStorageAdapter)
+	storage.On("SaveOrders", mock.Anything, orders).Return(nil).Once()
+	cacheAdapter := new(MockCacheAdapter)
+	cacheAdapter.On("InvalidateNotFound", ids).Once()
+	invalidator := new(MockCacheInvalidator)
+	invalidator.On("PublishInvalidation", mock.Anything, ids).
+		Return(fmt.Errorf("broker unavailable")).Once()
+
+	service := New(cacheAdapter, broker, storage)
+	service.UseInvalidator(invalidator, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	service.HandleOrdersEvents(ctx, 2, time.Second)
+
+	storage.AssertExpectations(t)
+	cacheAdapter.AssertExpectations(t)
+	invalidator.AssertExpectations(t)
+}
+
+func TestService_HandleInvalidations(t *testing.T) {
+	tests := []struct {
+		name      string
+		refresh   bool
+		ids       []string
+		mockSetup func(storage *MockStorageAdapter, cache *MockCacheAdapter)
+	}{
+		{
+			name: "drop",
+			ids:  []string{"first", "second"},
+			mockSetup: func(storage *MockStorageAdapter, cache *MockCacheAdapter) {
+				cache.On("InvalidateOrders", []string{"first", "second"}).Once()
+			},
+		},
+		{
+			name:    "refresh",
+			refresh: true,
+			ids:     []string{"first", "missing", "broken", "uncached"},
+			mockSetup: func(storage *MockStorageAdapter, cache *MockCacheAdapter) {
+				cache.On("HasOrder", "first").Return(true).Once()
+				cache.On("HasOrder", "missing").Return(true).Once()
+				cache.On("HasOrder", "broken").Return(true).Once()
+				storage.On("GetOrder", mock.Anything, "first").
+					Return(&models.Order{OrderUID: "first"}, nil).Once()
+				cache.On("RefreshOrder", "first", &models.Order{OrderUID: "first"}).Return(nil).Once()
+
+				storage.On("GetOrder", mock.Anything, "missing").
+					Return(nil, errs.ErrOrderNotFound).Once()
+				cache.On("InvalidateOrders", []string{"missing"}).Once()
+
+				storage.On("GetOrder", mock.Anything, "broken").
+					Return(nil, fmt.Errorf("connection refused")).Once()
+				cache.On("InvalidateOrders", []string{"broken"}).Once()
+
+				// orders nobody asked this instance for are not loaded
+				cache.On("HasOrder", "uncached").Return(false).Once()
+				cache.On("InvalidateOrders", []string{"uncached"}).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(MockStorageAdapter)
+			cacheAdapter := new(MockCacheAdapter)
+			tt.mockSetup(storage, cacheAdapter)
+			invalidator := new(MockCacheInvalidator)
+			invalidator.On("SubscribeInvalidations", mock.Anything).Return(tt.ids, nil).Once()
+
+			service := New(cacheAdapter, nil, storage)
+			service.UseKnownOrdersFilter(bloom.New(100, 0.01))
+			service.UseInvalidator(invalidator, tt.refresh)
+			require.NoError(t, service.HandleInvalidations(context.Background()))
+
+			for _, id := range tt.ids {
+				require.True(t, service.known.Test(id), "invalidated id should be added to the known orders filter")
+			}
+			storage.AssertExpectations(t)
+			cacheAdapter.AssertExpectations(t)
+			invalidator.AssertExpectations(t)
+		})
+	}
+
+	t.Run("subscription failed", func(t *testing.T) {
+		invalidator := new(MockCacheInvalidator)
+		invalidator.On("SubscribeInvalidations", mock.Anything).Return(nil, fmt.Errorf("broker unavailable"))
+
+		service := New(new(MockCacheAdapter), nil, new(MockStorageAdapter))
+		service.UseInvalidator(invalidator, false)
+		require.Error(t, service.HandleInvalidations(context.Background()))
+	})
+}
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    caller VARCHAR(100) NOT NULL,
+    claimed_caller VARCHAR(100),
+    action VARCHAR(50) NOT NULL,
+    order_uid VARCHAR(60),
+    route VARCHAR(200) NOT NULL,
+    result VARCHAR(20) NOT NULL,
+    fields TEXT[],
+    details TEXT
+);
+
+CREATE INDEX audit_log_occurred_at_idx ON audit_log (occurred_at);
+CREATE INDEX audit_log_order_uid_idx ON audit_log (order_uid, occurred_at);
+CREATE INDEX audit_log_caller_idx ON audit_log (caller, occurred_at);
+CREATE INDEX audit_log_claimed_caller_idx ON audit_log (claimed_caller, occurred_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_log;
+-- +goose StatementEnd
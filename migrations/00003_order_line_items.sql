@@ -0,0 +1,83 @@
+-- +goose Up
+-- +goose StatementBegin
+-- items used to be keyed by chrt_id alone, so every order after the first one
+-- with a given chrt_id silently got the payload of that first order.
+-- Line items now belong to their order and keep the payload as it was ingested
+ALTER TABLE order_items RENAME TO order_items_old;
+ALTER TABLE order_items_old RENAME CONSTRAINT order_items_pkey TO order_items_old_pkey;
+
+CREATE TABLE order_items (
+    order_uid VARCHAR(60) REFERENCES orders(order_uid) ON DELETE CASCADE NOT NULL,
+    line_no INTEGER NOT NULL,
+    chrt_id INTEGER NOT NULL,
+    track_number VARCHAR(50) NOT NULL,
+    price INTEGER NOT NULL,
+    rid VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    sale INTEGER NOT NULL,
+    size VARCHAR(10) NOT NULL,
+    total_price INTEGER NOT NULL,
+    nm_id INTEGER NOT NULL,
+    brand VARCHAR(50) NOT NULL,
+    status INTEGER NOT NULL,
+    PRIMARY KEY (order_uid, line_no)
+);
+
+CREATE INDEX order_items_chrt_id_idx ON order_items (chrt_id);
+
+-- the original payloads and the order of the lines are lost,
+-- the shared payload of every chrt_id is the best that is left
+INSERT INTO order_items (order_uid, line_no, chrt_id, track_number, price, rid, name,
+                         sale, size, total_price, nm_id, brand, status)
+SELECT oi.order_uid,
+       ROW_NUMBER() OVER (PARTITION BY oi.order_uid ORDER BY oi.item_chrt_id),
+       i.chrt_id, i.track_number, i.price, i.rid, i.name,
+       i.sale, i.size, i.total_price, i.nm_id, i.brand, i.status
+FROM order_items_old oi
+JOIN items i ON i.chrt_id = oi.item_chrt_id;
+
+DROP TABLE order_items_old;
+DROP TABLE items;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_items RENAME TO order_items_new;
+ALTER TABLE order_items_new RENAME CONSTRAINT order_items_pkey TO order_items_new_pkey;
+
+CREATE TABLE items (
+    chrt_id INTEGER PRIMARY KEY,
+    track_number VARCHAR(50) NOT NULL,
+    price INTEGER NOT NULL,
+    rid VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    sale INTEGER NOT NULL,
+    size VARCHAR(10) NOT NULL,
+    total_price INTEGER NOT NULL,
+    nm_id INTEGER NOT NULL,
+    brand VARCHAR(50) NOT NULL,
+    status INTEGER NOT NULL
+);
+
+CREATE TABLE order_items (
+    order_uid VARCHAR(60) REFERENCES orders(order_uid) NOT NULL,
+    item_chrt_id INTEGER REFERENCES items(chrt_id) NOT NULL,
+    PRIMARY KEY (order_uid, item_chrt_id)
+);
+
+-- the global table keeps a single payload per chrt_id, the earliest ingested one
+INSERT INTO items (chrt_id, track_number, price, rid, name, sale,
+                   size, total_price, nm_id, brand, status)
+SELECT DISTINCT ON (li.chrt_id)
+       li.chrt_id, li.track_number, li.price, li.rid, li.name, li.sale,
+       li.size, li.total_price, li.nm_id, li.brand, li.status
+FROM order_items_new li
+JOIN orders o ON o.order_uid = li.order_uid
+ORDER BY li.chrt_id, o.date_created, li.order_uid, li.line_no;
+
+INSERT INTO order_items (order_uid, item_chrt_id)
+SELECT DISTINCT order_uid, chrt_id
+FROM order_items_new;
+
+DROP TABLE order_items_new;
+-- +goose StatementEnd
@@ -0,0 +1,158 @@
+-- +goose Up
+-- +goose StatementBegin
+-- orders and their line items are range partitioned by month of date_created, the names
+-- of the partitions follow orders_YYYY_MM and order_items_YYYY_MM, see storage.PostgresPartitionAdapter.
+-- A partitioned table can only enforce keys that include date_created, so an order id is unique
+-- per creation time, which still rejects a redelivered order
+ALTER TABLE order_items RENAME TO order_items_old;
+ALTER TABLE order_items_old RENAME CONSTRAINT order_items_pkey TO order_items_old_pkey;
+ALTER INDEX order_items_chrt_id_idx RENAME TO order_items_old_chrt_id_idx;
+ALTER TABLE orders RENAME TO orders_old;
+ALTER TABLE orders_old RENAME CONSTRAINT orders_pkey TO orders_old_pkey;
+
+CREATE TABLE orders (
+    order_uid VARCHAR(60) NOT NULL,
+    track_number VARCHAR(60) NOT NULL,
+    entry VARCHAR(10) NOT NULL,
+    delivery_id INTEGER REFERENCES deliveries(id) NOT NULL,
+    payment_transaction VARCHAR(60) REFERENCES payments(transaction) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    internal_signature VARCHAR(100),
+    customer_id VARCHAR(50) NOT NULL,
+    delivery_service VARCHAR(50) NOT NULL,
+    shardkey VARCHAR(10) NOT NULL,
+    sm_id INTEGER NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    oof_shard VARCHAR(10) NOT NULL,
+    PRIMARY KEY (order_uid, date_created)
+) PARTITION BY RANGE (date_created);
+
+CREATE INDEX orders_date_created_idx ON orders (date_created);
+
+CREATE TABLE order_items (
+    order_uid VARCHAR(60) NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    line_no INTEGER NOT NULL,
+    chrt_id INTEGER NOT NULL,
+    track_number VARCHAR(50) NOT NULL,
+    price INTEGER NOT NULL,
+    rid VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    sale INTEGER NOT NULL,
+    size VARCHAR(10) NOT NULL,
+    total_price INTEGER NOT NULL,
+    nm_id INTEGER NOT NULL,
+    brand VARCHAR(50) NOT NULL,
+    status INTEGER NOT NULL,
+    PRIMARY KEY (order_uid, date_created, line_no),
+    CONSTRAINT order_items_order_fkey FOREIGN KEY (order_uid, date_created)
+        REFERENCES orders (order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (date_created);
+
+CREATE INDEX order_items_chrt_id_idx ON order_items (chrt_id);
+
+-- the default partitions take the orders no monthly partition exists for yet,
+-- they are kept out of retention
+CREATE TABLE orders_default PARTITION OF orders DEFAULT;
+CREATE TABLE order_items_default PARTITION OF order_items DEFAULT;
+
+DO $$
+DECLARE
+    month TIMESTAMP;
+BEGIN
+    FOR month IN
+        SELECT generate_series(
+            date_trunc('month', LEAST(MIN(date_created), now() AT TIME ZONE 'UTC')),
+            date_trunc('month', GREATEST(MAX(date_created), now() AT TIME ZONE 'UTC')),
+            INTERVAL '1 month'
+        )
+        FROM orders_old
+    LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF orders FOR VALUES FROM (%L) TO (%L)',
+            'orders_' || to_char(month, 'YYYY_MM'), month, month + INTERVAL '1 month');
+        EXECUTE format('CREATE TABLE %I PARTITION OF order_items FOR VALUES FROM (%L) TO (%L)',
+            'order_items_' || to_char(month, 'YYYY_MM'), month, month + INTERVAL '1 month');
+    END LOOP;
+END
+$$;
+
+INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_transaction,
+                    locale, internal_signature, customer_id, delivery_service,
+                    shardkey, sm_id, date_created, oof_shard)
+SELECT order_uid, track_number, entry, delivery_id, payment_transaction,
+       locale, internal_signature, customer_id, delivery_service,
+       shardkey, sm_id, date_created, oof_shard
+FROM orders_old;
+
+INSERT INTO order_items (order_uid, date_created, line_no, chrt_id, track_number, price, rid,
+                         name, sale, size, total_price, nm_id, brand, status)
+SELECT oi.order_uid, o.date_created, oi.line_no, oi.chrt_id, oi.track_number, oi.price, oi.rid,
+       oi.name, oi.sale, oi.size, oi.total_price, oi.nm_id, oi.brand, oi.status
+FROM order_items_old oi
+JOIN orders_old o ON o.order_uid = oi.order_uid;
+
+DROP TABLE order_items_old;
+DROP TABLE orders_old;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the orders of detached or dropped partitions are not restored
+ALTER TABLE order_items RENAME TO order_items_partitioned;
+ALTER TABLE order_items_partitioned RENAME CONSTRAINT order_items_pkey TO order_items_partitioned_pkey;
+ALTER INDEX order_items_chrt_id_idx RENAME TO order_items_partitioned_chrt_id_idx;
+ALTER TABLE orders RENAME TO orders_partitioned;
+ALTER TABLE orders_partitioned RENAME CONSTRAINT orders_pkey TO orders_partitioned_pkey;
+
+CREATE TABLE orders (
+    order_uid VARCHAR(60) PRIMARY KEY UNIQUE,
+    track_number VARCHAR(60) NOT NULL,
+    entry VARCHAR(10) NOT NULL,
+    delivery_id INTEGER REFERENCES deliveries(id) NOT NULL,
+    payment_transaction VARCHAR(60) REFERENCES payments(transaction) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    internal_signature VARCHAR(100),
+    customer_id VARCHAR(50) NOT NULL,
+    delivery_service VARCHAR(50) NOT NULL,
+    shardkey VARCHAR(10) NOT NULL,
+    sm_id INTEGER NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    oof_shard VARCHAR(10) NOT NULL
+);
+
+CREATE TABLE order_items (
+    order_uid VARCHAR(60) REFERENCES orders(order_uid) ON DELETE CASCADE NOT NULL,
+    line_no INTEGER NOT NULL,
+    chrt_id INTEGER NOT NULL,
+    track_number VARCHAR(50) NOT NULL,
+    price INTEGER NOT NULL,
+    rid VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    sale INTEGER NOT NULL,
+    size VARCHAR(10) NOT NULL,
+    total_price INTEGER NOT NULL,
+    nm_id INTEGER NOT NULL,
+    brand VARCHAR(50) NOT NULL,
+    status INTEGER NOT NULL,
+    PRIMARY KEY (order_uid, line_no)
+);
+
+CREATE INDEX order_items_chrt_id_idx ON order_items (chrt_id);
+
+INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_transaction,
+                    locale, internal_signature, customer_id, delivery_service,
+                    shardkey, sm_id, date_created, oof_shard)
+SELECT order_uid, track_number, entry, delivery_id, payment_transaction,
+       locale, internal_signature, customer_id, delivery_service,
+       shardkey, sm_id, date_created, oof_shard
+FROM orders_partitioned;
+
+INSERT INTO order_items (order_uid, line_no, chrt_id, track_number, price, rid,
+                         name, sale, size, total_price, nm_id, brand, status)
+SELECT order_uid, line_no, chrt_id, track_number, price, rid,
+       name, sale, size, total_price, nm_id, brand, status
+FROM order_items_partitioned;
+
+DROP TABLE order_items_partitioned;
+DROP TABLE orders_partitioned;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the keys of the partitioned orders include date_created, so they only keep an order id unique
+-- per creation time. order_uids keeps it unique across all partitions, it is written along with
+-- the orders and emptied by retiring their partition, see storage.PostgresPartitionAdapter
+CREATE TABLE order_uids (
+    order_uid VARCHAR(60) PRIMARY KEY
+);
+
+INSERT INTO order_uids (order_uid)
+SELECT DISTINCT order_uid FROM orders;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS order_uids;
+-- +goose StatementEnd
@@ -0,0 +1,67 @@
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+// Filter is a thread-safe Bloom filter of strings. Test never returns false
+// for an added key, and returns true for a key that was not added with about
+// the false positive rate the filter was created for, while it holds at most
+// the expected number of keys
+type Filter struct {
+	words  []atomic.Uint64
+	bits   uint64
+	hashes uint64
+	seed1  maphash.Seed
+	seed2  maphash.Seed
+}
+
+// New creates a filter sized for n keys and the false positive rate p
+//   - n: expected number of keys
+//   - p: target false positive rate, between 0 and 1 exclusive
+func New(n int, p float64) *Filter {
+	n = max(n, 1)
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	bits := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	bits = max((bits+63)/64*64, 64)
+	hashes := uint64(max(1, math.Round(float64(bits)/float64(n)*math.Ln2)))
+
+	return &Filter{
+		words:  make([]atomic.Uint64, bits/64),
+		bits:   bits,
+		hashes: hashes,
+		seed1:  maphash.MakeSeed(),
+		seed2:  maphash.MakeSeed(),
+	}
+}
+
+// Add inserts the key into the filter
+func (f *Filter) Add(key string) {
+	h1, h2 := f.hash(key)
+	for i := range f.hashes {
+		bit := (h1 + i*h2) % f.bits
+		f.words[bit/64].Or(1 << (bit % 64))
+	}
+}
+
+// Test reports whether the key may have been added
+func (f *Filter) Test(key string) bool {
+	h1, h2 := f.hash(key)
+	for i := range f.hashes {
+		bit := (h1 + i*h2) % f.bits
+		if f.words[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hash returns the two hashes the bit positions are derived from, h2 is odd so they never repeat early
+func (f *Filter) hash(key string) (uint64, uint64) {
+	return maphash.String(f.seed1, key), maphash.String(f.seed2, key) | 1
+}
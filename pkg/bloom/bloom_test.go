@@ -0,0 +1,70 @@
+package bloom
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		p    float64
+	}{
+		{name: "one percent", n: 10000, p: 0.01},
+		{name: "one per mille", n: 10000, p: 0.001},
+		{name: "invalid rate falls back to default", n: 1000, p: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(tt.n, tt.p)
+			for i := range tt.n {
+				f.Add("known" + strconv.Itoa(i))
+			}
+
+			for i := range tt.n {
+				require.True(t, f.Test("known"+strconv.Itoa(i)), "added key should always be reported")
+			}
+
+			want := tt.p
+			if want >= 1 {
+				want = 0.01
+			}
+			falsePositives := 0
+			const probes = 100000
+			for i := range probes {
+				if f.Test("unknown" + strconv.Itoa(i)) {
+					falsePositives++
+				}
+			}
+			require.Less(t, float64(falsePositives)/probes, 2*want, "false positive rate too high")
+		})
+	}
+}
+
+func TestFilter_Concurrent(t *testing.T) {
+	f := New(1000, 0.01)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				key := strconv.Itoa(g*100 + i)
+				f.Add(key)
+				assert.True(t, f.Test(key))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range 800 {
+		require.True(t, f.Test(strconv.Itoa(i)))
+	}
+}
@@ -8,4 +8,6 @@ var (
 	ErrOrderNotFound      = errors.New("order not found")
 	ErrEmptyOrderUID      = errors.New("empty order uid")
 	ErrOrderItemsNotFound = errors.New("order items not found")
+
+	ErrAuditUnavailable = errors.New("audit trail unavailable")
 )
@@ -0,0 +1,7 @@
+package health
+
+type Config struct {
+	CacheTTLMs        int `env:"CACHE_TTL_MS"         env-default:"2000"  json:"cache_ttl_ms"         yaml:"cache_ttl_ms"`
+	TimeoutMs         int `env:"TIMEOUT_MS"           env-default:"1000"  json:"timeout_ms"           yaml:"timeout_ms"`
+	MaxHeartbeatAgeMs int `env:"MAX_HEARTBEAT_AGE_MS" env-default:"30000" json:"max_heartbeat_age_ms" yaml:"max_heartbeat_age_ms"`
+}
@@ -0,0 +1,9 @@
+package health
+
+import "errors"
+
+var (
+	ErrNoHeartbeat    = errors.New("no heartbeat yet")
+	ErrStaleHeartbeat = errors.New("heartbeat is stale")
+	ErrNotReady       = errors.New("not ready")
+)
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// CheckFunc reports a dependency as healthy by returning nil
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of a single check
+type Result struct {
+	Name       string    `json:"name"`
+	Status     Status    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Report aggregates the results of all registered checks
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+
+	mu   sync.Mutex
+	last Result
+}
+
+// Registry holds named dependency checks and caches their results,
+// so frequent probes do not overload the dependencies
+type Registry struct {
+	mu       sync.RWMutex
+	checks   []*check
+	cacheTTL time.Duration
+	timeout  time.Duration
+}
+
+// NewRegistry creates an empty Registry
+//   - cacheTTL: how long a check result is reused before the check runs again
+//   - timeout: max duration of a single check
+func NewRegistry(cacheTTL, timeout time.Duration) *Registry {
+	return &Registry{
+		cacheTTL: cacheTTL,
+		timeout:  timeout,
+	}
+}
+
+// Register adds a named check to the registry
+func (r *Registry) Register(name string, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, &check{name: name, fn: fn})
+}
+
+// Check runs every registered check, reusing results younger than the cache TTL.
+// The report is up only if all checks are up. The checks run detached from ctx, bounded by the
+// registry timeout only, since their results are cached for the probes that come after this one
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.checks
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, res := range results {
+		if res.Status != StatusUp {
+			report.Status = StatusDown
+			break
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c *check) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.last.CheckedAt.IsZero() && time.Since(c.last.CheckedAt) < r.cacheTTL {
+		return c.last
+	}
+
+	// a probe that gives up must not cache its cancellation for the others
+	ctx = context.WithoutCancel(ctx)
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := c.fn(ctx)
+
+	res := Result{
+		Name:       c.name,
+		Status:     StatusUp,
+		DurationMs: time.Since(start).Milliseconds(),
+		CheckedAt:  start,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	c.last = res
+
+	return res
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		wantStatus Status
+	}{
+		{
+			name: "all checks up",
+			checks: map[string]CheckFunc{
+				"first":  func(context.Context) error { return nil },
+				"second": func(context.Context) error { return nil },
+			},
+			wantStatus: StatusUp,
+		},
+		{
+			name: "one check down",
+			checks: map[string]CheckFunc{
+				"first":  func(context.Context) error { return nil },
+				"second": func(context.Context) error { return errors.New("connection refused") },
+			},
+			wantStatus: StatusDown,
+		},
+		{
+			name:       "no checks",
+			checks:     map[string]CheckFunc{},
+			wantStatus: StatusUp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(0, time.Second)
+			for name, fn := range tt.checks {
+				r.Register(name, fn)
+			}
+
+			report := r.Check(context.Background())
+
+			require.Equal(t, tt.wantStatus, report.Status)
+			require.Len(t, report.Checks, len(tt.checks))
+			for _, res := range report.Checks {
+				if res.Status == StatusDown {
+					assert.NotEmpty(t, res.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_CheckCached(t *testing.T) {
+	var calls atomic.Int32
+	r := NewRegistry(time.Minute, time.Second)
+	r.Register("counted", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	for range 5 {
+		r.Check(context.Background())
+	}
+
+	require.Equal(t, int32(1), calls.Load(), "check should run once within cache TTL")
+}
+
+func TestRegistry_CheckTimeout(t *testing.T) {
+	r := NewRegistry(0, 10*time.Millisecond)
+	r.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := r.Check(context.Background())
+
+	require.Equal(t, StatusDown, report.Status)
+}
+
+func TestRegistry_CheckCanceledProbe(t *testing.T) {
+	r := NewRegistry(time.Minute, time.Second)
+	r.Register("ctx", func(ctx context.Context) error {
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, StatusUp, r.Check(ctx).Status, "a canceled probe should not fail the checks")
+	require.Equal(t, StatusUp, r.Check(context.Background()).Status)
+}
+
+func TestHeartbeat_Check(t *testing.T) {
+	h := NewHeartbeat()
+	check := h.Check(50 * time.Millisecond)
+
+	require.ErrorIs(t, check(context.Background()), ErrNoHeartbeat)
+
+	h.Beat()
+	require.NoError(t, check(context.Background()))
+
+	time.Sleep(60 * time.Millisecond)
+	require.ErrorIs(t, check(context.Background()), ErrStaleHeartbeat)
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat tracks the last time a background loop reported it is alive
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+func NewHeartbeat() *Heartbeat {
+	return &Heartbeat{}
+}
+
+// Beat records that the loop is alive now
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Last returns the time of the last beat, zero if there was none
+func (h *Heartbeat) Last() time.Time {
+	nanos := h.last.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Check returns a CheckFunc that fails when the last beat is older than maxAge
+func (h *Heartbeat) Check(maxAge time.Duration) CheckFunc {
+	return func(_ context.Context) error {
+		last := h.Last()
+		if last.IsZero() {
+			return ErrNoHeartbeat
+		}
+		if age := time.Since(last); age > maxAge {
+			return fmt.Errorf("%w: last beat %s ago", ErrStaleHeartbeat, age.Truncate(time.Millisecond))
+		}
+		return nil
+	}
+}
+
+// Flag marks a one-shot startup step, such as migrations or cache warm-up, as done
+type Flag struct {
+	done atomic.Bool
+}
+
+func NewFlag() *Flag {
+	return &Flag{}
+}
+
+func (f *Flag) Set() {
+	f.done.Store(true)
+}
+
+func (f *Flag) IsSet() bool {
+	return f.done.Load()
+}
+
+// Check returns a CheckFunc that fails until the flag is set
+func (f *Flag) Check() CheckFunc {
+	return func(_ context.Context) error {
+		if !f.IsSet() {
+			return ErrNotReady
+		}
+		return nil
+	}
+}
@@ -2,24 +2,26 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jaam8/wb_tech_school_l0/pkg/logger"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 )
 
 type Config struct {
-	Host    string   `env:"HOST"    env-default:"kafka" yaml:"host"`
-	Port    uint16   `env:"PORT"    env-default:"9092"  yaml:"port"`
-	Brokers []string `env:"BROKERS" env-separator:","   yaml:"brokers"`
-
-	MinBytes       int `env:"MIN_BYTES"          env-default:"10"      yaml:"min_bytes"`
-	MaxBytes       int `env:"MAX_BYTES"          env-default:"1048576" yaml:"max_bytes"` // 1MB
-	MaxWaitMs      int `env:"MAX_WAIT_MS"        env-default:"500"     yaml:"max_wait_ms"`
-	CommitInterval int `env:"COMMIT_INTERVAL_MS" env-default:"1000"    yaml:"commit_interval_ms"`
+	Host    string   `env:"HOST"    env-default:"kafka" json:"host"    yaml:"host"`
+	Port    uint16   `env:"PORT"    env-default:"9092"  json:"port"    yaml:"port"`
+	Brokers []string `env:"BROKERS" env-separator:","   json:"brokers" yaml:"brokers"`
+
+	MinBytes       int `env:"MIN_BYTES"          env-default:"10"      json:"min_bytes"          yaml:"min_bytes"`
+	MaxBytes       int `env:"MAX_BYTES"          env-default:"1048576" json:"max_bytes"          yaml:"max_bytes"` // 1MB
+	MaxWaitMs      int `env:"MAX_WAIT_MS"        env-default:"500"     json:"max_wait_ms"        yaml:"max_wait_ms"`
+	CommitInterval int `env:"COMMIT_INTERVAL_MS" env-default:"1000"    json:"commit_interval_ms" yaml:"commit_interval_ms"`
 }
 
 func NewReader(ctx context.Context, cfg Config, topic, groupID string) *kafka.Reader {
@@ -41,6 +43,48 @@ func NewReader(ctx context.Context, cfg Config, topic, groupID string) *kafka.Re
 	return r
 }
 
+// NewBroadcastReader creates a reader in a consumer group of its own, named groupPrefix
+// followed by a random suffix, so that every instance reads every message of the topic.
+// It starts from the newest messages, and the offsets of its group expire
+// with the offsets retention of the brokers once the instance is gone
+func NewBroadcastReader(ctx context.Context, cfg Config, topic, groupPrefix string) *kafka.Reader {
+	groupID := groupPrefix + "-" + uuid.NewString()
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        cfg.Brokers,
+		Topic:          topic,
+		GroupID:        groupID,
+		StartOffset:    kafka.LastOffset,
+		MinBytes:       cfg.MinBytes,
+		MaxBytes:       cfg.MaxBytes,
+		MaxWait:        time.Duration(cfg.MaxWaitMs) * time.Millisecond,
+		CommitInterval: time.Duration(cfg.CommitInterval) * time.Millisecond,
+	})
+	logger.Info(ctx, "connected to Kafka topic",
+		zap.Strings("brokers", cfg.Brokers),
+		zap.String("topic", topic),
+		zap.String("group_id", groupID),
+	)
+
+	return r
+}
+
+// WaitForAssignment blocks until the group reader has been assigned its partitions, checking every interval.
+// The reader fetches from then on even if nothing reads it yet. It resets the stats counters of the reader
+func WaitForAssignment(ctx context.Context, r *kafka.Reader, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for r.Stats().Rebalances == 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
 func NewWriter(ctx context.Context, cfg Config, topic string) *kafka.Writer {
 	w := &kafka.Writer{
 		Addr:         kafka.TCP(cfg.Brokers...),
@@ -58,7 +102,34 @@ func NewWriter(ctx context.Context, cfg Config, topic string) *kafka.Writer {
 	return w
 }
 
-func CreateTopicIfNotExists(cfg Config, topic string, numPartitions, replicationFactor int) error {
+// Ping checks that at least one of the configured brokers is reachable
+func Ping(ctx context.Context, cfg Config) error {
+	var err error
+	for _, broker := range cfg.Brokers {
+		var conn *kafka.Conn
+		conn, err = kafka.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			continue
+		}
+		_, err = conn.Brokers()
+		conn.Close()
+		if err == nil {
+			return nil
+		}
+	}
+	if err == nil {
+		return errors.New("no kafka brokers configured")
+	}
+
+	return err
+}
+
+// Compacted makes the brokers keep only the last message of every key of a topic
+var Compacted = kafka.ConfigEntry{ConfigName: "cleanup.policy", ConfigValue: "compact"}
+
+func CreateTopicIfNotExists(
+	cfg Config, topic string, numPartitions, replicationFactor int, configs ...kafka.ConfigEntry,
+) error {
 	conn, err := kafka.Dial("tcp", cfg.Brokers[0])
 	if err != nil {
 		return err
@@ -82,13 +153,16 @@ func CreateTopicIfNotExists(cfg Config, topic string, numPartitions, replication
 		Topic:             topic,
 		NumPartitions:     numPartitions,
 		ReplicationFactor: replicationFactor,
+		ConfigEntries:     configs,
 	})
 }
 
-func CreateTopicWithRetry(cfg Config, topic string, numPartitions, replicationFactor, maxRetries int) error {
+func CreateTopicWithRetry(
+	cfg Config, topic string, numPartitions, replicationFactor, maxRetries int, configs ...kafka.ConfigEntry,
+) error {
 	var err error
 	for i := range maxRetries {
-		err = CreateTopicIfNotExists(cfg, topic, numPartitions, replicationFactor)
+		err = CreateTopicIfNotExists(cfg, topic, numPartitions, replicationFactor, configs...)
 		if err == nil {
 			return nil
 		}
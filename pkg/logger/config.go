@@ -0,0 +1,9 @@
+package logger
+
+type Config struct {
+	Level              string            `env:"LEVEL"               env-default:"info"      json:"level"               yaml:"level"`
+	ComponentLevels    map[string]string `env:"COMPONENT_LEVELS"    json:"component_levels" yaml:"component_levels"` // e.g. consumer:debug,storage:warn
+	Encoding           string            `env:"ENCODING"            env-default:"json"      json:"encoding"            yaml:"encoding"`
+	SamplingInitial    int               `env:"SAMPLING_INITIAL"    env-default:"0"         json:"sampling_initial"    yaml:"sampling_initial"` // 0 disables sampling
+	SamplingThereafter int               `env:"SAMPLING_THEREAFTER" env-default:"100"       json:"sampling_thereafter" yaml:"sampling_thereafter"`
+}
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels is shared by every logger of the process, so a change
+// made at runtime is picked up by all of them
+var levels = &levelRegistry{
+	global:    zap.NewAtomicLevelAt(zapcore.InfoLevel),
+	overrides: make(map[string]zapcore.Level),
+}
+
+type levelRegistry struct {
+	global zap.AtomicLevel
+
+	mu        sync.RWMutex
+	overrides map[string]zapcore.Level
+}
+
+// ParseLevel parses a level name such as "debug", "warn" or "error" (case-insensitive)
+func ParseLevel(level string) (zapcore.Level, error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "warning" {
+		level = WarnLvl
+	}
+
+	var lvl zapcore.Level
+	if level == "" {
+		return lvl, errors.New("empty log level")
+	}
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return lvl, fmt.Errorf("unknown log level %q", level)
+	}
+
+	return lvl, nil
+}
+
+// AtomicLevel returns the process-wide level shared by all loggers
+func AtomicLevel() zap.AtomicLevel {
+	return levels.global
+}
+
+// SetLevel changes the process-wide level
+func SetLevel(level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	levels.global.SetLevel(lvl)
+
+	return nil
+}
+
+// SetComponentLevel overrides the level for loggers created with WithComponent
+func SetComponentLevel(component, level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	levels.overrides[component] = lvl
+
+	return nil
+}
+
+// ResetComponentLevel drops the override, so the component follows the process-wide level again
+func ResetComponentLevel(component string) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	delete(levels.overrides, component)
+}
+
+// SetComponentLevels replaces all component overrides at once
+func SetComponentLevels(overrides map[string]string) error {
+	parsed := make(map[string]zapcore.Level, len(overrides))
+	for component, level := range overrides {
+		lvl, err := ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component, err)
+		}
+		parsed[component] = lvl
+	}
+
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	levels.overrides = parsed
+
+	return nil
+}
+
+// Levels returns the process-wide level and the component overrides
+func Levels() (string, map[string]string) {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+
+	overrides := make(map[string]string, len(levels.overrides))
+	for component, lvl := range levels.overrides {
+		overrides[component] = lvl.String()
+	}
+
+	return levels.global.String(), overrides
+}
+
+func (r *levelRegistry) enabler(component string) zapcore.LevelEnabler {
+	return zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		r.mu.RLock()
+		override, ok := r.overrides[component]
+		r.mu.RUnlock()
+		if ok {
+			return override.Enabled(lvl)
+		}
+		return r.global.Enabled(lvl)
+	})
+}
+
+// leveledCore filters entries with its own enabler instead of the one
+// the wrapped core was built with, which lets components log below the process-wide level
+type leveledCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c leveledCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl)
+}
+
+func (c leveledCore) With(fields []zapcore.Field) zapcore.Core {
+	return leveledCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c leveledCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+func withEnabler(enabler zapcore.LevelEnabler) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if lc, ok := core.(leveledCore); ok {
+			core = lc.Core
+		}
+		return leveledCore{Core: core, enabler: enabler}
+	})
+}
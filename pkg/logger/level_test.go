@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{name: "debug", level: "debug", want: zapcore.DebugLevel},
+		{name: "info", level: "info", want: zapcore.InfoLevel},
+		{name: "warn", level: "warn", want: zapcore.WarnLevel},
+		{name: "warning alias", level: "warning", want: zapcore.WarnLevel},
+		{name: "error", level: "error", want: zapcore.ErrorLevel},
+		{name: "upper case with spaces", level: " ERROR ", want: zapcore.ErrorLevel},
+		{name: "fatal", level: "fatal", want: zapcore.FatalLevel},
+		{name: "unknown", level: "verbose", wantErr: true},
+		{name: "empty", level: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.level)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithComponent_LevelOverride(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := context.WithValue(context.Background(), KeyForLogger,
+		&Logger{l: zap.New(core, withEnabler(levels.global))})
+
+	require.NoError(t, SetLevel(InfoLvl))
+	require.NoError(t, SetComponentLevel("consumer", DebugLvl))
+	t.Cleanup(func() { ResetComponentLevel("consumer") })
+
+	consumerCtx := WithComponent(ctx, "consumer")
+
+	Debug(ctx, "root debug")
+	Debug(consumerCtx, "consumer debug")
+	require.Equal(t, 1, logs.FilterMessage("consumer debug").Len())
+	require.Equal(t, 0, logs.FilterMessage("root debug").Len())
+
+	require.NoError(t, SetLevel(ErrorLvl))
+	ResetComponentLevel("consumer")
+
+	Info(consumerCtx, "consumer info")
+	require.Equal(t, 0, logs.FilterMessage("consumer info").Len(),
+		"component should follow the process-wide level after reset")
+
+	require.NoError(t, SetLevel(InfoLvl))
+}
@@ -2,6 +2,8 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -13,80 +15,145 @@ type key string
 const (
 	KeyForLogger    key    = "logger"
 	KeyForRequestID key    = "request_id"
-	KeyForLogLevel  key    = "log_level"
 	DebugLvl        string = "debug"
 	InfoLvl         string = "info"
+	WarnLvl         string = "warn"
+	ErrorLvl        string = "error"
+	ComponentKey    string = "component"
+	JSONEncoding    string = "json"
+	ConsoleEncoding string = "console"
 )
 
+// Logger is an immutable wrapper over zap.Logger,
+// children are derived with With and never change their parent
 type Logger struct {
 	l *zap.Logger
 }
 
-func NewLogger(logLevel string) (*Logger, error) {
+// root is the process-wide logger, used whenever the context carries none
+var root atomic.Pointer[Logger]
+
+// NewLogger builds a logger bound to the process-wide level,
+// use Init to apply the levels from cfg as well
+func NewLogger(cfg Config) (*Logger, error) {
 	config := zap.NewProductionConfig()
 	config.EncoderConfig.TimeKey = "time"
 	config.EncoderConfig.EncodeTime = zapcore.TimeEncoder(func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 		enc.AppendString(t.Format("2006-01-02 15:04:05"))
 	})
+	// the core accepts everything, filtering is done by the shared level registry
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+	switch cfg.Encoding {
+	case "", JSONEncoding:
+		config.Encoding = JSONEncoding
+	case ConsoleEncoding:
+		config.Encoding = ConsoleEncoding
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	default:
+		return nil, fmt.Errorf("unknown log encoding %q", cfg.Encoding)
+	}
 
-	var level zapcore.Level
-	switch logLevel {
-	case DebugLvl:
-		level = zap.DebugLevel
-	case InfoLvl:
-		level = zap.InfoLevel
+	config.Sampling = nil
+	if cfg.SamplingInitial > 0 {
+		// zap drops every entry past the initial ones when thereafter is zero
+		if cfg.SamplingThereafter <= 0 {
+			return nil, fmt.Errorf("sampling thereafter must be positive, got %d", cfg.SamplingThereafter)
+		}
+		config.Sampling = &zap.SamplingConfig{
+			Initial:    cfg.SamplingInitial,
+			Thereafter: cfg.SamplingThereafter,
+		}
 	}
-	config.Level.SetLevel(level)
 
-	logger, err := config.Build(zap.AddCaller(), zap.AddCallerSkip(1))
+	logger, err := config.Build(zap.AddCaller(), zap.AddCallerSkip(1), withEnabler(levels.global))
 	if err != nil {
 		return nil, err
 	}
 
-	loggerStruct := &Logger{l: logger}
+	return &Logger{l: logger}, nil
+}
 
-	return loggerStruct, nil
+// FromZap wraps an existing zap logger, binding it to the process-wide level
+func FromZap(l *zap.Logger) *Logger {
+	return &Logger{l: l.WithOptions(withEnabler(levels.global))}
 }
 
-func New(ctx context.Context) (context.Context, error) {
-	logLevel, ok := ctx.Value(KeyForLogLevel).(string)
-	if !ok {
-		logLevel = "debug"
+// Init applies the levels from cfg and builds the root logger once at startup
+func Init(cfg Config) (*Logger, error) {
+	if cfg.Level != "" {
+		if err := SetLevel(cfg.Level); err != nil {
+			return nil, err
+		}
+	}
+	if err := SetComponentLevels(cfg.ComponentLevels); err != nil {
+		return nil, err
 	}
 
-	loggerStruct, err := NewLogger(logLevel)
+	l, err := NewLogger(cfg)
 	if err != nil {
 		return nil, err
 	}
+	SetRoot(l)
 
-	ctx = context.WithValue(ctx, KeyForLogger, loggerStruct)
+	return l, nil
+}
+
+// SetRoot replaces the process-wide logger
+func SetRoot(l *Logger) {
+	root.Store(l)
+}
+
+// Root returns the process-wide logger. Until Init is called it is a logger with default settings
+func Root() *Logger {
+	if l := root.Load(); l != nil {
+		return l
+	}
+
+	l, err := NewLogger(Config{})
+	if err != nil {
+		l = &Logger{l: zap.NewNop()}
+	}
+	root.CompareAndSwap(nil, l)
 
-	return ctx, nil
+	return root.Load()
 }
 
+// ContextWithLogger returns a copy of ctx carrying l
+func ContextWithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, KeyForLogger, l)
+}
+
+// GetLoggerFromCtx returns the logger carried by ctx or the root logger if there is none
 func GetLoggerFromCtx(ctx context.Context) *Logger {
-	return ctx.Value(KeyForLogger).(*Logger)
+	if ctx != nil {
+		if l, ok := ctx.Value(KeyForLogger).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+
+	return Root()
+}
+
+// ContextWithRequestID returns a copy of ctx whose log entries carry the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, KeyForRequestID, requestID)
 }
 
 func TryAppendRequestIDFromContext(ctx context.Context, fields []zap.Field) []zap.Field {
-	if ctx.Value(KeyForRequestID) != nil {
-		fields = append(fields, zap.String(string(KeyForRequestID), ctx.Value(KeyForRequestID).(string)))
+	if ctx == nil {
+		return fields
+	}
+	if requestID, ok := ctx.Value(KeyForRequestID).(string); ok {
+		fields = append(fields, zap.String(string(KeyForRequestID), requestID))
 	}
 
 	return fields
 }
 
-func GetOrCreateLoggerFromCtx(ctx context.Context) *Logger {
-	logger := GetLoggerFromCtx(ctx)
-	if logger == nil {
-		logLevel, ok := ctx.Value(KeyForLogLevel).(string)
-		if !ok {
-			logLevel = "debug"
-		}
-		logger, _ = NewLogger(logLevel)
-	}
-
-	return logger
+// Sync flushes buffered entries of the root logger
+func Sync() error {
+	return Root().l.Sync()
 }
 
 func Debug(ctx context.Context, msg string, fields ...zap.Field) {
@@ -114,10 +181,21 @@ func Fatal(ctx context.Context, msg string, fields ...zap.Field) {
 	GetLoggerFromCtx(ctx).l.Fatal(msg, fields...)
 }
 
+// With returns a context carrying a child logger with the given fields,
+// the logger of the parent context is left untouched
 func With(ctx context.Context, fields ...zap.Field) context.Context {
-	currentLogger := GetLoggerFromCtx(ctx)
-	newZapLogger := currentLogger.l.With(fields...)
-	currentLogger.l = newZapLogger
+	child := &Logger{l: GetLoggerFromCtx(ctx).l.With(fields...)}
+
+	return ContextWithLogger(ctx, child)
+}
+
+// WithComponent returns a context whose logger is tagged with the component name
+// and follows the component level override, if one is set
+func WithComponent(ctx context.Context, component string) context.Context {
+	child := &Logger{
+		l: GetLoggerFromCtx(ctx).l.WithOptions(withEnabler(levels.enabler(component))).
+			With(zap.String(ComponentKey, component)),
+	}
 
-	return ctx
+	return ContextWithLogger(ctx, child)
 }
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWith_DoesNotMutateParent(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	parent := ContextWithLogger(context.Background(), &Logger{l: zap.New(core)})
+
+	child := With(parent, zap.String("order_uid", "first"))
+	With(parent, zap.String("order_uid", "second"))
+
+	Info(parent, "parent")
+	Info(child, "child")
+
+	require.Empty(t, logs.FilterMessage("parent").All()[0].Context,
+		"parent logger should not get fields of its children")
+	require.Equal(t, []zap.Field{zap.String("order_uid", "first")},
+		logs.FilterMessage("child").All()[0].Context)
+}
+
+func TestGetLoggerFromCtx_Fallback(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "context without logger", ctx: context.Background()},
+		{name: "nil logger in context", ctx: context.WithValue(context.Background(), KeyForLogger, (*Logger)(nil))},
+		{name: "nil context", ctx: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := GetLoggerFromCtx(tt.ctx)
+			require.NotNil(t, l)
+			require.Same(t, Root(), l)
+			require.NotPanics(t, func() { Info(tt.ctx, "fallback") })
+		})
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "defaults", cfg: Config{}},
+		{name: "console encoding", cfg: Config{Encoding: ConsoleEncoding}},
+		{name: "sampling", cfg: Config{SamplingInitial: 100, SamplingThereafter: 10}},
+		{name: "sampling without thereafter", cfg: Config{SamplingInitial: 100}, wantErr: true},
+		{name: "unknown encoding", cfg: Config{Encoding: "xml"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLogger(tt.cfg)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, l)
+		})
+	}
+}
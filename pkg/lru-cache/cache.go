@@ -0,0 +1,60 @@
+package lrucache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is implemented by both the single-lock InMemoryCache and the ShardedCache
+type Cache[K comparable, V any] interface {
+	Set(key K, value V) error
+	SetWithTTL(key K, value V, ttl time.Duration) error
+	Get(key K) (V, error)
+	GetOrLoad(ctx context.Context, key K, loader LoaderFunc[V]) (V, LoadStatus, error)
+	Delete(key K) error
+	Peek(key K) (value V, expiredAt time.Time, err error)
+	Purge()
+	Len() int
+	Keys() []K
+	Range(fn func(key K, value V, expiredAt time.Time) bool)
+	Resize(cap int) int
+	TTLRemaining(key K) (time.Duration, error)
+	Stats() Stats
+	OnEvict(hook EvictFunc[K, V])
+	SetMaxCost(maxCost int64, cost CostFunc[V])
+	StartCleanup(ctx context.Context, interval time.Duration)
+	Snapshotter
+}
+
+var (
+	_ Cache[string, any] = (*InMemoryCache[string, any])(nil)
+	_ Cache[string, any] = (*ShardedCache[string, any])(nil)
+)
+
+// NewFromConfig creates a ShardedCache if cfg.Shards is greater than one and an InMemoryCache otherwise.
+// A negative cfg.TTL disables expiration
+func NewFromConfig[K comparable, V any](cfg Config) (Cache[K, V], error) {
+	ttl := time.Duration(cfg.TTL) * time.Minute
+	if cfg.TTL < 0 {
+		ttl = NoExpiry
+	}
+
+	policy, err := ParsePolicy(cfg.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []Option{WithPolicy(policy), WithLoadTimeout(time.Duration(cfg.LoadTimeoutMs) * time.Millisecond)}
+	if cfg.StaleGraceSec > 0 {
+		opts = append(opts, WithStaleWhileRevalidate(time.Duration(cfg.StaleGraceSec)*time.Second))
+	}
+	if cfg.Sliding {
+		opts = append(opts, WithSlidingExpiration())
+	}
+
+	if cfg.Shards > 1 {
+		return NewShardedCache[K, V](cfg.Shards, cfg.Capacity, ttl, opts...), nil
+	}
+
+	return NewInMemoryCache[K, V](cfg.Capacity, ttl, opts...), nil
+}
@@ -1,7 +1,16 @@
 package lrucache
 
 type Config struct {
-	CleanupInterval int `env:"CLEANUP_INTERVAL" env-default:"5"    yaml:"cleanup_interval"`
-	TTL             int `env:"TTL"              env-default:"15"   yaml:"ttl"`
-	Capacity        int `env:"CAPACITY"         env-default:"1000" yaml:"capacity"`
+	CleanupInterval int    `env:"CLEANUP_INTERVAL" env-default:"5"     json:"cleanup_interval" yaml:"cleanup_interval"`
+	TTL             int    `env:"TTL"              env-default:"15"    json:"ttl"              yaml:"ttl"` // minutes, negative for no expiry
+	Capacity        int    `env:"CAPACITY"         env-default:"1000"  json:"capacity"         yaml:"capacity"`
+	Shards          int    `env:"SHARDS"           env-default:"1"     json:"shards"           yaml:"shards"` // more than one enables ShardedCache
+	Sliding         bool   `env:"SLIDING"          env-default:"false" json:"sliding"          yaml:"sliding"`
+	Policy          string `env:"POLICY"           env-default:"lru"   json:"policy"           yaml:"policy"`          // lru, lfu or tinylfu
+	LoadTimeoutMs   int    `env:"LOAD_TIMEOUT_MS"  env-default:"5000"  json:"load_timeout_ms"  yaml:"load_timeout_ms"` // zero for no timeout
+	SnapshotPath    string `env:"SNAPSHOT_PATH"    env-default:""      json:"snapshot_path"    yaml:"snapshot_path"`   // empty disables snapshots
+	StaleGraceSec   int    `env:"STALE_GRACE_SEC"  env-default:"0"     json:"stale_grace_sec"  yaml:"stale_grace_sec"` // zero disables stale-while-revalidate
+	// MaxCostMB bounds the estimated size of cached values, zero disables it.
+	// Set Capacity to zero to bound the cache by size only
+	MaxCostMB int `env:"MAX_COST_MB"      env-default:"0"     json:"max_cost_mb"      yaml:"max_cost_mb"`
 }
@@ -3,7 +3,10 @@ package lrucache
 import "errors"
 
 var (
-	ErrNotFound       = errors.New("item not found")
-	ErrExpired        = errors.New("item expired")
-	ErrUnexpectedType = errors.New("unexpected type")
+	ErrNotFound        = errors.New("item not found")
+	ErrExpired         = errors.New("item expired")
+	ErrUnexpectedType  = errors.New("unexpected type")
+	ErrUnknownPolicy   = errors.New("unknown eviction policy")
+	ErrInvalidSnapshot = errors.New("invalid cache snapshot")
+	ErrTooCostly       = errors.New("item costs more than the cache budget")
 )
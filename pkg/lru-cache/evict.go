@@ -0,0 +1,54 @@
+package lrucache
+
+// EvictionReason tells why an item left the cache
+type EvictionReason uint8
+
+const (
+	// EvictionCapacity is reported for the least recently used item dropped to make room for a new one
+	EvictionCapacity EvictionReason = iota + 1
+	// EvictionExpired is reported for items removed by the cleanup after their TTL passed
+	EvictionExpired
+	// EvictionDeleted is reported for items removed by Delete or Purge
+	EvictionDeleted
+	// EvictionReplaced is reported with the old value when Set overwrites an existing key
+	EvictionReplaced
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionExpired:
+		return "expired"
+	case EvictionDeleted:
+		return "deleted"
+	case EvictionReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// CostFunc estimates the cost of a value, such as its size in bytes
+type CostFunc[V any] func(value V) int64
+
+// EvictFunc is called for every item that leaves the cache
+type EvictFunc[K comparable, V any] func(key K, value V, reason EvictionReason)
+
+// eviction is an item removed under the lock, whose hooks are run after the lock is released
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+func notify[K comparable, V any](hooks []EvictFunc[K, V], evictions ...eviction[K, V]) {
+	for _, ev := range evictions {
+		if ev.reason == 0 {
+			continue
+		}
+		for _, hook := range hooks {
+			hook(ev.key, ev.value, ev.reason)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package lrucache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type evicted struct {
+	key    string
+	value  string
+	reason EvictionReason
+}
+
+type evictRecorder struct {
+	mu     sync.Mutex
+	events []evicted
+}
+
+func (r *evictRecorder) hook(key, value string, reason EvictionReason) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evicted{key: key, value: value, reason: reason})
+}
+
+func (r *evictRecorder) get() []evicted {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]evicted(nil), r.events...)
+}
+
+func TestInMemoryCache_OnEvict(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(c *InMemoryCache[string, string])
+		want []evicted
+	}{
+		{
+			name: "capacity",
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "value1")
+				c.Set("key2", "value2")
+				c.Set("key3", "value3")
+			},
+			want: []evicted{{"key1", "value1", EvictionCapacity}},
+		},
+		{
+			name: "replaced",
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "value1")
+				c.Set("key1", "value2")
+			},
+			want: []evicted{{"key1", "value1", EvictionReplaced}},
+		},
+		{
+			name: "deleted",
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "value1")
+				c.Delete("key1")
+				c.Delete("missing")
+			},
+			want: []evicted{{"key1", "value1", EvictionDeleted}},
+		},
+		{
+			name: "purged",
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "value1")
+				c.Set("key2", "value2")
+				c.Purge()
+			},
+			want: []evicted{
+				{"key2", "value2", EvictionDeleted},
+				{"key1", "value1", EvictionDeleted},
+			},
+		},
+		{
+			name: "get does not evict",
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "value1")
+				c.Get("key1")
+				c.Get("missing")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rec evictRecorder
+			cache := NewInMemoryCache[string, string](2, time.Minute)
+			cache.OnEvict(rec.hook)
+
+			tt.ops(cache)
+
+			require.Equal(t, tt.want, rec.get())
+		})
+	}
+}
+
+func TestInMemoryCache_OnEvictExpired(t *testing.T) {
+	var rec evictRecorder
+	cache := NewInMemoryCache[string, string](10, 10*time.Millisecond)
+	cache.OnEvict(rec.hook)
+	require.NoError(t, cache.Set("key1", "value1"))
+	require.NoError(t, cache.SetWithTTL("key2", "value2", NoExpiry))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache.StartCleanup(ctx, 5*time.Millisecond)
+
+	require.Eventually(t, func() bool { return len(rec.get()) > 0 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, []evicted{{"key1", "value1", EvictionExpired}}, rec.get())
+}
+
+func TestInMemoryCache_OnEvictOutsideLock(t *testing.T) {
+	cache := NewInMemoryCache[string, string](1, time.Minute)
+	done := make(chan struct{})
+	cache.OnEvict(func(key, _ string, _ EvictionReason) {
+		// would deadlock if the hook ran under the lock
+		_, _, err := cache.Peek(key)
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Equal(t, 1, cache.Len())
+		close(done)
+	})
+
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hook was not called")
+	}
+}
+
+func TestShardedCache_OnEvict(t *testing.T) {
+	var rec evictRecorder
+	cache := NewShardedCache[string, string](4, 100, time.Minute)
+	cache.OnEvict(rec.hook)
+
+	require.NoError(t, cache.Set("key1", "value1"))
+	require.NoError(t, cache.Delete("key1"))
+
+	require.Equal(t, []evicted{{"key1", "value1", EvictionDeleted}}, rec.get())
+}
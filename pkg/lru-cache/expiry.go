@@ -0,0 +1,90 @@
+package lrucache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// cleanupChunk bounds the number of items removed while holding the lock,
+// so readers wait for at most one chunk during a large expiration
+const cleanupChunk = 256
+
+// expiryHeap is a min-heap of the entries that can expire, ordered by expiredAt,
+// so cleanup only looks at the entries that have actually expired
+type expiryHeap[K comparable, V any] []*entry[K, V]
+
+func (h expiryHeap[K, V]) Len() int { return len(h) }
+
+func (h expiryHeap[K, V]) Less(i, j int) bool {
+	return h[i].expiredAt.Before(h[j].expiredAt)
+}
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.heapIndex = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.heapIndex = -1
+	*h = old[:n-1]
+	return e
+}
+
+// schedule must be called with the lock held after the deadline of e changes
+func (c *InMemoryCache[K, V]) schedule(e *entry[K, V]) {
+	switch {
+	case e.expiredAt.IsZero():
+		c.unschedule(e)
+	case e.heapIndex >= 0:
+		heap.Fix(&c.expiry, e.heapIndex)
+	default:
+		heap.Push(&c.expiry, e)
+	}
+}
+
+// unschedule must be called with the lock held
+func (c *InMemoryCache[K, V]) unschedule(e *entry[K, V]) {
+	if e.heapIndex >= 0 {
+		heap.Remove(&c.expiry, e.heapIndex)
+	}
+}
+
+// removeExpired drops the expired items in chunks of cleanupChunk,
+// releasing the lock and running the hooks between the chunks
+func (c *InMemoryCache[K, V]) removeExpired() {
+	for {
+		c.mu.Lock()
+		var evicted []eviction[K, V]
+		now := time.Now()
+		removed := 0
+		// items within the stale grace window are kept for GetOrLoad
+		deadline := now.Add(-c.staleGrace)
+		for removed < cleanupChunk && len(c.expiry) > 0 && c.expiry[0].expired(deadline) {
+			e := c.expiry[0]
+			c.remove(e)
+			c.expirations.Add(1)
+			removed++
+			if len(c.hooks) > 0 {
+				evicted = append(evicted, eviction[K, V]{key: e.key, value: e.value, reason: EvictionExpired})
+			}
+		}
+		hooks := c.hooks
+		c.mu.Unlock()
+
+		notify(hooks, evicted...)
+		if removed < cleanupChunk {
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package lrucache
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// lfuPolicy keeps an LRU list per use count, so both access and eviction are O(1)
+type lfuPolicy[K comparable, V any] struct {
+	buckets map[uint32]*lruList[K, V]
+	// minFreq may point to an emptied bucket after remove, selectVictim looks for the next one
+	minFreq uint32
+}
+
+func newLFUPolicy[K comparable, V any]() *lfuPolicy[K, V] {
+	return &lfuPolicy[K, V]{buckets: make(map[uint32]*lruList[K, V])}
+}
+
+func (p *lfuPolicy[K, V]) add(e *entry[K, V]) {
+	e.freq = 1
+	p.bucket(1).PushFront(e)
+	p.minFreq = 1
+}
+
+func (p *lfuPolicy[K, V]) access(e *entry[K, V]) {
+	freq := e.freq
+	p.unlink(e)
+	if e.freq < math.MaxUint32 {
+		e.freq++
+	}
+	p.bucket(e.freq).PushFront(e)
+	if p.minFreq == freq && p.buckets[freq] == nil {
+		p.minFreq = e.freq
+	}
+}
+
+func (p *lfuPolicy[K, V]) miss(K) {}
+
+func (p *lfuPolicy[K, V]) remove(e *entry[K, V]) {
+	p.unlink(e)
+}
+
+// selectVictim spares the added entry, a new item must not be the victim of its own insertion
+func (p *lfuPolicy[K, V]) selectVictim(added *entry[K, V]) *entry[K, V] {
+	if len(p.buckets) == 0 {
+		return nil
+	}
+	if p.buckets[p.minFreq] == nil {
+		p.minFreq = p.nextFreq(0)
+	}
+
+	e := p.buckets[p.minFreq].Back()
+	if e == added && len(p.buckets) > 1 {
+		// the new item is the only one used once, evict the least used of the others
+		return p.buckets[p.nextFreq(p.minFreq)].Back()
+	}
+	return e
+}
+
+// nextFreq returns the smallest use count with a bucket above after
+func (p *lfuPolicy[K, V]) nextFreq(after uint32) uint32 {
+	next := uint32(math.MaxUint32)
+	for freq := range p.buckets {
+		if freq > after {
+			next = min(next, freq)
+		}
+	}
+	return next
+}
+
+// each visits the buckets from the most used one down, and each bucket from its most recently used entry
+func (p *lfuPolicy[K, V]) each(fn func(e *entry[K, V])) {
+	freqs := slices.Sorted(maps.Keys(p.buckets))
+	for _, freq := range slices.Backward(freqs) {
+		p.buckets[freq].each(fn)
+	}
+}
+
+func (p *lfuPolicy[K, V]) reset() {
+	p.buckets = make(map[uint32]*lruList[K, V])
+	p.minFreq = 0
+}
+
+func (p *lfuPolicy[K, V]) resize(int) {}
+
+func (p *lfuPolicy[K, V]) bucket(freq uint32) *lruList[K, V] {
+	l, ok := p.buckets[freq]
+	if !ok {
+		l = newLRUList[K, V]()
+		p.buckets[freq] = l
+	}
+	return l
+}
+
+// unlink removes e from its bucket and drops the bucket once it is empty
+func (p *lfuPolicy[K, V]) unlink(e *entry[K, V]) {
+	l := p.buckets[e.freq]
+	l.Remove(e)
+	if l.Len() == 0 {
+		delete(p.buckets, e.freq)
+	}
+}
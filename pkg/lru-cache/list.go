@@ -0,0 +1,133 @@
+package lrucache
+
+import "time"
+
+// entry is a single cache item and a node of the recency list at the same time,
+// so the hot path neither boxes values into interfaces nor allocates list elements
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	// ttl is kept to move the deadline on reads in sliding mode
+	ttl time.Duration
+	// expiredAt is zero for entries that never expire
+	expiredAt time.Time
+	// cost is zero unless the cache has a cost budget
+	cost int64
+	// freq is the use count kept by the LFU policy
+	freq uint32
+	// segment is the list the W-TinyLFU policy keeps the entry in
+	segment uint8
+	// heapIndex is the position in the expiry heap, -1 for entries that never expire
+	heapIndex int
+
+	prev, next *entry[K, V]
+}
+
+// setTTL treats every negative ttl as NoExpiry
+func (e *entry[K, V]) setTTL(now time.Time, ttl time.Duration) {
+	if ttl < 0 {
+		ttl = NoExpiry
+	}
+	e.ttl = ttl
+	e.touch(now)
+}
+
+// touch moves the deadline to now + ttl
+func (e *entry[K, V]) touch(now time.Time) {
+	if e.ttl == NoExpiry {
+		e.expiredAt = time.Time{}
+		return
+	}
+	e.expiredAt = now.Add(e.ttl)
+}
+
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return !e.expiredAt.IsZero() && now.After(e.expiredAt)
+}
+
+// lruList is an intrusive doubly linked list with a sentinel root,
+// the front is the most recently used entry
+type lruList[K comparable, V any] struct {
+	root entry[K, V]
+	len  int
+}
+
+func newLRUList[K comparable, V any]() *lruList[K, V] {
+	l := &lruList[K, V]{}
+	l.Init()
+	return l
+}
+
+// Init empties the list
+func (l *lruList[K, V]) Init() {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	l.len = 0
+}
+
+func (l *lruList[K, V]) Len() int {
+	return l.len
+}
+
+// Front returns the most recently used entry or nil if the list is empty
+func (l *lruList[K, V]) Front() *entry[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.next
+}
+
+// Back returns the least recently used entry or nil if the list is empty
+func (l *lruList[K, V]) Back() *entry[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// Next returns the entry following e towards the back, or nil
+func (l *lruList[K, V]) Next(e *entry[K, V]) *entry[K, V] {
+	if e.next == &l.root {
+		return nil
+	}
+	return e.next
+}
+
+// each calls fn for every entry from the front to the back
+func (l *lruList[K, V]) each(fn func(e *entry[K, V])) {
+	for e := l.Front(); e != nil; e = l.Next(e) {
+		fn(e)
+	}
+}
+
+func (l *lruList[K, V]) PushFront(e *entry[K, V]) {
+	l.insertAfter(e, &l.root)
+	l.len++
+}
+
+func (l *lruList[K, V]) MoveToFront(e *entry[K, V]) {
+	if l.root.next == e {
+		return
+	}
+	l.unlink(e)
+	l.insertAfter(e, &l.root)
+}
+
+func (l *lruList[K, V]) Remove(e *entry[K, V]) {
+	l.unlink(e)
+	e.prev = nil
+	e.next = nil
+	l.len--
+}
+
+func (l *lruList[K, V]) insertAfter(e, at *entry[K, V]) {
+	e.prev = at
+	e.next = at.next
+	at.next.prev = e
+	at.next = e
+}
+
+func (l *lruList[K, V]) unlink(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+}
@@ -0,0 +1,213 @@
+package lrucache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LoaderFunc loads the value of a missing key and the TTL to cache it with, zero for the default TTL
+type LoaderFunc[V any] func(ctx context.Context) (value V, ttl time.Duration, err error)
+
+// call is a load in flight, shared by every caller asking for the same key
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// invalidated is set under loadMu by Delete and Purge of the key while it loads,
+	// the value is then only returned and not cached, so it cannot bring back a deleted key
+	invalidated bool
+}
+
+// LoadStatus tells how GetOrLoad got the value
+type LoadStatus uint8
+
+const (
+	// LoadMiss means the value was loaded
+	LoadMiss LoadStatus = iota
+	// LoadHit means the value was served from the cache
+	LoadHit
+	// LoadStale means the value expired within the stale grace window
+	// and was served from the cache while being reloaded in the background
+	LoadStale
+)
+
+func (s LoadStatus) String() string {
+	switch s {
+	case LoadHit:
+		return "hit"
+	case LoadStale:
+		return "stale"
+	default:
+		return "miss"
+	}
+}
+
+// GetOrLoad returns the cached value of the key, or loads and caches it on a miss.
+// Concurrent callers missing the same key share a single load and all get its value or error,
+// errors are not cached.
+//
+// With WithStaleWhileRevalidate an item expired within the grace window is returned
+// right away with LoadStale, and a single background load refreshes it.
+// If that load fails, the stale value keeps being served until the window passes.
+//
+// The load runs detached from the callers, so one of them giving up does not fail it for the others,
+// and is bounded by WithLoadTimeout. ctx bounds how long this caller waits for it
+func (c *InMemoryCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[V]) (V, LoadStatus, error) {
+	value, status := c.getOrStale(key)
+	switch status {
+	case LoadHit:
+		return value, LoadHit, nil
+	case LoadStale:
+		c.startLoad(ctx, key, loader)
+		return value, LoadStale, nil
+	}
+
+	cl := c.startLoad(ctx, key, loader)
+	select {
+	case <-cl.done:
+		return cl.value, LoadMiss, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, LoadMiss, ctx.Err()
+	}
+}
+
+// getOrStale is Get that also returns values expired within the stale grace window
+func (c *InMemoryCache[K, V]) getOrStale(key K) (V, LoadStatus) {
+	var zero V
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok || c.isNil(e.value) {
+		c.misses.Add(1)
+		c.policy.miss(key)
+		return zero, LoadMiss
+	}
+
+	now := time.Now()
+	if e.expired(now) {
+		if c.staleGrace <= 0 || e.expired(now.Add(-c.staleGrace)) {
+			c.misses.Add(1)
+			c.policy.miss(key)
+			return zero, LoadMiss
+		}
+		c.hits.Add(1)
+		c.staleHits.Add(1)
+		c.policy.access(e)
+		return e.value, LoadStale
+	}
+
+	if c.sliding {
+		e.touch(now)
+		c.schedule(e)
+	}
+	c.policy.access(e)
+	c.hits.Add(1)
+
+	return e.value, LoadHit
+}
+
+// startLoad returns the load of the key in flight or starts a new one. A load that finished
+// after the caller missed has already cached the value, it is returned as a finished call
+func (c *InMemoryCache[K, V]) startLoad(ctx context.Context, key K, loader LoaderFunc[V]) *call[V] {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	if cl, ok := c.loads[key]; ok {
+		return cl
+	}
+
+	cl := &call[V]{done: make(chan struct{})}
+	if value, ok := c.fresh(key); ok {
+		cl.value = value
+		close(cl.done)
+		return cl
+	}
+	c.loads[key] = cl
+	go c.load(context.WithoutCancel(ctx), key, cl, loader)
+
+	return cl
+}
+
+// fresh returns the value of the key unless it is missing or expired, without counting it
+func (c *InMemoryCache[K, V]) fresh(key K) (V, bool) {
+	var zero V
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.items[key]
+	if !ok || c.isNil(e.value) || e.expired(time.Now()) {
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+func (c *InMemoryCache[K, V]) load(ctx context.Context, key K, cl *call[V], loader LoaderFunc[V]) {
+	var ttl time.Duration
+	defer func() {
+		if r := recover(); r != nil {
+			cl.err = fmt.Errorf("loader of key %v panicked: %v", key, r)
+		}
+
+		// the value is cached before the call is dropped, so later callers either hit it or join the call.
+		// loadMu is held while storing it, so a Delete either invalidates the call before or removes the value after
+		var (
+			evicted []eviction[K, V]
+			hooks   []EvictFunc[K, V]
+		)
+		c.loadMu.Lock()
+		if cl.err == nil && !cl.invalidated {
+			c.mu.Lock()
+			// a value that cannot be cached is still returned to the callers
+			evicted, _ = c.set(key, cl.value, ttl)
+			hooks = c.hooks
+			c.mu.Unlock()
+		}
+		delete(c.loads, key)
+		c.loadMu.Unlock()
+		close(cl.done)
+
+		notify(hooks, evicted...)
+	}()
+
+	if c.loadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.loadTimeout)
+		defer cancel()
+	}
+
+	value, loadedTTL, err := loader(ctx)
+	if err != nil {
+		cl.err = err
+		return
+	}
+	cl.value = value
+
+	ttl = loadedTTL
+	if ttl == 0 {
+		ttl = c.TTL
+	}
+}
+
+// invalidateLoad keeps the load of the key in flight, if any, from caching its value
+func (c *InMemoryCache[K, V]) invalidateLoad(key K) {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	if cl, ok := c.loads[key]; ok {
+		cl.invalidated = true
+	}
+}
+
+// invalidateLoads is invalidateLoad for every load in flight
+func (c *InMemoryCache[K, V]) invalidateLoads() {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	for _, cl := range c.loads {
+		cl.invalidated = true
+	}
+}
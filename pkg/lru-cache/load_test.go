@@ -0,0 +1,385 @@
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCache_GetOrLoad(t *testing.T) {
+	errLoad := errors.New("load failed")
+
+	tests := []struct {
+		name       string
+		setup      func(c *InMemoryCache[string, string])
+		loader     LoaderFunc[string]
+		opts       []Option
+		want       string
+		wantStatus LoadStatus
+		wantErr    error
+		wantPanic  bool
+		wantCache  error
+	}{
+		{
+			name: "hit does not load",
+			setup: func(c *InMemoryCache[string, string]) {
+				c.Set("key", "cached")
+			},
+			loader: func(context.Context) (string, time.Duration, error) {
+				return "", 0, errors.New("loader should not be called")
+			},
+			want:       "cached",
+			wantStatus: LoadHit,
+		},
+		{
+			name: "miss loads and caches",
+			loader: func(context.Context) (string, time.Duration, error) {
+				return "loaded", 0, nil
+			},
+			want: "loaded",
+		},
+		{
+			name: "error is returned and not cached",
+			loader: func(context.Context) (string, time.Duration, error) {
+				return "", 0, errLoad
+			},
+			wantErr:   errLoad,
+			wantCache: ErrNotFound,
+		},
+		{
+			name: "load is bounded by the load timeout",
+			opts: []Option{WithLoadTimeout(10 * time.Millisecond)},
+			loader: func(ctx context.Context) (string, time.Duration, error) {
+				<-ctx.Done()
+				return "", 0, ctx.Err()
+			},
+			wantErr:   context.DeadlineExceeded,
+			wantCache: ErrNotFound,
+		},
+		{
+			name: "panic is returned as error",
+			loader: func(context.Context) (string, time.Duration, error) {
+				panic("boom")
+			},
+			wantPanic: true,
+			wantCache: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, string](10, time.Minute, tt.opts...)
+			if tt.setup != nil {
+				tt.setup(cache)
+			}
+
+			got, status, err := cache.GetOrLoad(context.Background(), "key", tt.loader)
+			if tt.wantPanic {
+				require.ErrorContains(t, err, "panicked: boom")
+			} else {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantStatus, status)
+
+			cached, err := cache.Get("key")
+			require.ErrorIs(t, err, tt.wantCache)
+			if tt.wantCache == nil {
+				require.Equal(t, tt.want, cached)
+			}
+		})
+	}
+}
+
+func TestInMemoryCache_GetOrLoadDeduplicates(t *testing.T) {
+	const callers = 50
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "value is shared"},
+		{name: "error is shared", err: ErrNotFound, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, string](10, time.Minute)
+
+			var loads atomic.Int32
+			release := make(chan struct{})
+			loader := func(context.Context) (string, time.Duration, error) {
+				loads.Add(1)
+				<-release
+				return "loaded", 0, tt.err
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, callers)
+			for range callers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, _, err := cache.GetOrLoad(context.Background(), "key", loader)
+					errs <- err
+				}()
+			}
+
+			require.Eventually(t, func() bool {
+				cache.loadMu.Lock()
+				defer cache.loadMu.Unlock()
+				return len(cache.loads) == 1
+			}, time.Second, time.Millisecond)
+			// let the rest of the callers join the load before it finishes
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(errs)
+
+			require.Equal(t, int32(1), loads.Load(), "concurrent misses should share one load")
+			for err := range errs {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInMemoryCache_GetOrLoadCallerCancel(t *testing.T) {
+	cache := NewInMemoryCache[string, string](10, time.Minute)
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (string, time.Duration, error) {
+		select {
+		case <-release:
+			return "loaded", 0, nil
+		case <-ctx.Done():
+			return "", 0, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := cache.GetOrLoad(ctx, "key", loader)
+		first <- err
+	}()
+	require.Eventually(t, func() bool {
+		cache.loadMu.Lock()
+		defer cache.loadMu.Unlock()
+		return len(cache.loads) == 1
+	}, time.Second, time.Millisecond)
+
+	second := make(chan string, 1)
+	go func() {
+		value, _, _ := cache.GetOrLoad(context.Background(), "key", loader)
+		second <- value
+	}()
+
+	cancel()
+	require.ErrorIs(t, <-first, context.Canceled)
+
+	close(release)
+	require.Equal(t, "loaded", <-second, "the load should outlive the caller that started it")
+}
+
+func TestInMemoryCache_GetOrLoadTTL(t *testing.T) {
+	cache := NewInMemoryCache[string, string](10, time.Minute)
+
+	_, _, err := cache.GetOrLoad(context.Background(), "key", func(context.Context) (string, time.Duration, error) {
+		return "loaded", NoExpiry, nil
+	})
+	require.NoError(t, err)
+
+	_, expiredAt, err := cache.Peek("key")
+	require.NoError(t, err)
+	require.True(t, expiredAt.IsZero(), "TTL returned by the loader should be used")
+}
+
+// a load that finished between the miss of a caller and its startLoad must not be repeated
+func TestInMemoryCache_StartLoadAfterFinishedLoad(t *testing.T) {
+	cache := NewInMemoryCache[string, string](10, time.Minute)
+	require.NoError(t, cache.Set("key", "loaded"))
+
+	cl := cache.startLoad(context.Background(), "key", func(context.Context) (string, time.Duration, error) {
+		t.Error("loader should not be called for a cached key")
+		return "", 0, nil
+	})
+
+	select {
+	case <-cl.done:
+	default:
+		t.Fatal("call should be finished")
+	}
+	require.Equal(t, "loaded", cl.value)
+	require.NoError(t, cl.err)
+	require.Empty(t, cache.loads)
+}
+
+func TestInMemoryCache_GetOrLoadStale(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wait       time.Duration
+		want       string
+		wantStatus LoadStatus
+	}{
+		{
+			name:       "fresh item is a hit",
+			opts:       []Option{WithStaleWhileRevalidate(time.Minute)},
+			want:       "old",
+			wantStatus: LoadHit,
+		},
+		{
+			name:       "expired within grace is served stale",
+			opts:       []Option{WithStaleWhileRevalidate(time.Minute)},
+			wait:       30 * time.Millisecond,
+			want:       "old",
+			wantStatus: LoadStale,
+		},
+		{
+			name:       "expired past grace is loaded",
+			opts:       []Option{WithStaleWhileRevalidate(10 * time.Millisecond)},
+			wait:       50 * time.Millisecond,
+			want:       "new",
+			wantStatus: LoadMiss,
+		},
+		{
+			name:       "expired without grace is loaded",
+			wait:       30 * time.Millisecond,
+			want:       "new",
+			wantStatus: LoadMiss,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, string](10, 20*time.Millisecond, tt.opts...)
+			require.NoError(t, cache.Set("key", "old"))
+			time.Sleep(tt.wait)
+
+			got, status, err := cache.GetOrLoad(context.Background(), "key", func(context.Context) (string, time.Duration, error) {
+				return "new", time.Minute, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
+func TestInMemoryCache_GetOrLoadStaleRefresh(t *testing.T) {
+	cache := NewInMemoryCache[string, string](10, 10*time.Millisecond, WithStaleWhileRevalidate(time.Minute))
+	require.NoError(t, cache.Set("key", "old"))
+	time.Sleep(20 * time.Millisecond)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(context.Context) (string, time.Duration, error) {
+		loads.Add(1)
+		<-release
+		return "new", time.Minute, nil
+	}
+
+	for range 10 {
+		got, status, err := cache.GetOrLoad(context.Background(), "key", loader)
+		require.NoError(t, err)
+		require.Equal(t, "old", got, "stale value should be returned without waiting for the refresh")
+		require.Equal(t, LoadStale, status)
+	}
+
+	// expired items within the grace window survive cleanup
+	cache.removeExpired()
+
+	close(release)
+	require.Eventually(t, func() bool {
+		got, err := cache.Get("key")
+		return err == nil && got == "new"
+	}, time.Second, time.Millisecond)
+	require.Equal(t, int32(1), loads.Load(), "stale reads should share one refresh")
+	require.Equal(t, uint64(10), cache.Stats().StaleHits)
+}
+
+func TestInMemoryCache_GetOrLoadStaleRefreshFails(t *testing.T) {
+	cache := NewInMemoryCache[string, string](10, 10*time.Millisecond, WithStaleWhileRevalidate(time.Minute))
+	require.NoError(t, cache.Set("key", "old"))
+	time.Sleep(20 * time.Millisecond)
+
+	failing := func(context.Context) (string, time.Duration, error) {
+		return "", 0, errors.New("storage is down")
+	}
+	_, status, err := cache.GetOrLoad(context.Background(), "key", failing)
+	require.NoError(t, err)
+	require.Equal(t, LoadStale, status)
+
+	require.Eventually(t, func() bool {
+		cache.loadMu.Lock()
+		defer cache.loadMu.Unlock()
+		return len(cache.loads) == 0
+	}, time.Second, time.Millisecond)
+
+	got, status, err := cache.GetOrLoad(context.Background(), "key", failing)
+	require.NoError(t, err)
+	require.Equal(t, "old", got, "stale value should be kept when the refresh fails")
+	require.Equal(t, LoadStale, status)
+}
+
+// a key deleted while it loads must not be brought back by the load
+func TestInMemoryCache_GetOrLoadDeleteDuringLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(cache *InMemoryCache[string, string])
+	}{
+		{
+			name: "delete",
+			invalidate: func(cache *InMemoryCache[string, string]) {
+				require.ErrorIs(t, cache.Delete("key"), ErrNotFound)
+			},
+		},
+		{
+			name: "purge",
+			invalidate: func(cache *InMemoryCache[string, string]) {
+				cache.Purge()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, string](10, time.Minute)
+			started := make(chan struct{})
+			release := make(chan struct{})
+			loader := func(context.Context) (string, time.Duration, error) {
+				close(started)
+				<-release
+				return "old", 0, nil
+			}
+
+			var (
+				value string
+				err   error
+			)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				value, _, err = cache.GetOrLoad(context.Background(), "key", loader)
+			}()
+
+			<-started
+			tt.invalidate(cache)
+			close(release)
+			<-done
+
+			require.NoError(t, err)
+			require.Equal(t, "old", value, "callers of the load still get its value")
+			_, err = cache.Get("key")
+			require.ErrorIs(t, err, ErrNotFound)
+			require.Empty(t, cache.loads)
+		})
+	}
+}
@@ -1,134 +1,424 @@
 package lrucache
 
 import (
-	"container/list"
 	"context"
 	"fmt"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// InMemoryCache implements a thread-safe LRU cache with TTL support
-type InMemoryCache struct {
+// NoExpiry passed as a TTL keeps the item until it is evicted or deleted,
+// any other negative TTL is treated the same way
+const NoExpiry time.Duration = -1
+
+// InMemoryCache implements a thread-safe cache with TTL support,
+// evicting the least recently used items unless another Policy is set with WithPolicy
+type InMemoryCache[K comparable, V any] struct {
 	mu    sync.RWMutex
-	items map[interface{}]*list.Element
-	list  *list.List
-	cap   int
-	TTL   time.Duration
+	items map[K]*entry[K, V]
+	// policy orders the items for eviction
+	policy policy[K, V]
+	// expiry orders the items that can expire by their deadline
+	expiry expiryHeap[K, V]
+	cap    int
+	TTL    time.Duration
+
+	// sliding moves the deadline of an item forward on every successful Get
+	sliding bool
+	// staleGrace is how long after expiring an item can still be served by GetOrLoad
+	staleGrace time.Duration
+
+	// nilable is set when V is an interface type, so a nil value can be rejected by Set
+	nilable bool
+
+	// cost is nil unless a cost budget is set with SetMaxCost
+	cost      CostFunc[V]
+	maxCost   int64
+	totalCost int64
+
+	// loads are the loads of GetOrLoad in flight, guarded by their own lock
+	// so that a slow load does not block readers of other keys
+	loadMu      sync.Mutex
+	loads       map[K]*call[V]
+	loadTimeout time.Duration
+
+	// hooks are only appended to, a copy of the slice header taken
+	// under the lock can be safely used after it is released
+	hooks []EvictFunc[K, V]
+
+	hits        atomic.Uint64
+	staleHits   atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
 }
 
-// item represents a single cache entry
-type item struct {
-	key       interface{}
-	value     interface{}
-	expiredAt time.Time
+// Stats is a point-in-time view of the cache counters
+type Stats struct {
+	Len         int    `json:"len"`
+	Capacity    int    `json:"capacity"`
+	Cost        int64  `json:"cost"`
+	MaxCost     int64  `json:"max_cost"`
+	Hits        uint64 `json:"hits"`
+	StaleHits   uint64 `json:"stale_hits"` // part of Hits served stale by GetOrLoad
+	Misses      uint64 `json:"misses"`
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
 }
 
-// New creates and returns a new InMemoryCache instance
+// NewInMemoryCache creates and returns a new InMemoryCache instance
 //   - cap: max number of items the cache can hold
-//   - ttl: time to live for duration for each cached item
-func New(cap int, ttl time.Duration) *InMemoryCache {
-	return &InMemoryCache{
-		items: make(map[interface{}]*list.Element),
-		list:  list.New(),
-		cap:   cap,
-		TTL:   ttl,
+//   - ttl: default time to live for each cached item, NoExpiry to keep items until evicted
+//   - opts: optional behaviour, such as WithSlidingExpiration
+func NewInMemoryCache[K comparable, V any](cap int, ttl time.Duration, opts ...Option) *InMemoryCache[K, V] {
+	o := newOptions(opts)
+
+	return &InMemoryCache[K, V]{
+		items:       make(map[K]*entry[K, V]),
+		policy:      newPolicy[K, V](o.policy, cap),
+		loads:       make(map[K]*call[V]),
+		loadTimeout: o.loadTimeout,
+		cap:         cap,
+		TTL:         ttl,
+		sliding:     o.sliding,
+		staleGrace:  o.staleGrace,
+		nilable:     reflect.TypeFor[V]().Kind() == reflect.Interface,
 	}
 }
 
-// Set inserts or updates a key-value pair in the cache
+// Set inserts or updates a key-value pair in the cache with the default TTL
 //
 // If the key already exists, its value and their TTL are updated.
 // When the cache exceeds its capacity, the least recently used item is removed
-func (c *InMemoryCache) Set(key, value interface{}) error {
+func (c *InMemoryCache[K, V]) Set(key K, value V) error {
+	return c.SetWithTTL(key, value, c.TTL)
+}
+
+// SetWithTTL is Set with a TTL for this item only, NoExpiry keeps it until evicted.
+// An item that alone costs more than the budget of SetMaxCost is not cached and ErrTooCostly
+// is returned, a previous value of the key is dropped
+func (c *InMemoryCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	evicted, err := c.set(key, value, ttl)
+	hooks := c.hooks
+	c.mu.Unlock()
 
-	if elem, ok := c.items[key]; ok {
-		c.list.MoveToFront(elem)
-		itm, ok := elem.Value.(*item)
-		if !ok {
-			return ErrUnexpectedType
+	notify(hooks, evicted...)
+
+	return err
+}
+
+// set must be called with the lock held, it returns the replaced and evicted items
+func (c *InMemoryCache[K, V]) set(key K, value V, ttl time.Duration) ([]eviction[K, V], error) {
+	var evicted []eviction[K, V]
+
+	now := time.Now()
+	e, ok := c.items[key]
+	if !ok && c.isNil(value) {
+		return nil, fmt.Errorf("cannot set nil value to key %v", key)
+	}
+
+	var cost int64
+	if c.cost != nil {
+		cost = c.cost(value)
+	}
+	if c.maxCost > 0 && cost > c.maxCost {
+		// evicting the others could not make room for it, keep them instead
+		if ok {
+			c.remove(e)
+			evicted = append(evicted, eviction[K, V]{key: key, value: e.value, reason: EvictionReplaced})
 		}
-		itm.value = value
-		itm.expiredAt = time.Now().Add(c.TTL)
-		return nil
+		return evicted, ErrTooCostly
 	}
 
-	if value == nil {
-		return fmt.Errorf("cannot set nil value to key %v", key)
+	if ok {
+		evicted = append(evicted, eviction[K, V]{key: key, value: e.value, reason: EvictionReplaced})
+		c.policy.access(e)
+		e.value = value
+		e.setTTL(now, ttl)
+		c.schedule(e)
+		c.setCost(e, cost)
+		return c.evict(evicted, nil), nil
 	}
 
-	itm := &item{
+	e = &entry[K, V]{
 		key:       key,
 		value:     value,
-		expiredAt: time.Now().Add(c.TTL),
-	}
-	elem := c.list.PushFront(itm)
-	c.items[key] = elem
-
-	if c.cap > 0 && c.list.Len() > c.cap {
-		last := c.list.Back()
-		if last != nil {
-			c.list.Remove(last)
-			lastItem, ok := last.Value.(*item)
-			if !ok {
-				return ErrUnexpectedType
-			}
-			delete(c.items, lastItem.key)
+		heapIndex: -1,
+	}
+	e.setTTL(now, ttl)
+	c.schedule(e)
+	c.setCost(e, cost)
+	c.items[key] = e
+	c.policy.add(e)
+
+	return c.evict(evicted, e), nil
+}
+
+// setCost must be called with the lock held whenever the value of e changes
+func (c *InMemoryCache[K, V]) setCost(e *entry[K, V], cost int64) {
+	c.totalCost += cost - e.cost
+	e.cost = cost
+}
+
+// evict must be called with the lock held, it drops least recently used items
+// until both the capacity and the cost budget are met and appends them to evicted.
+// added is the entry the caller just inserted, nil if there is none
+func (c *InMemoryCache[K, V]) evict(evicted []eviction[K, V], added *entry[K, V]) []eviction[K, V] {
+	for (c.cap > 0 && len(c.items) > c.cap) || (c.maxCost > 0 && c.totalCost > c.maxCost) {
+		last := c.policy.selectVictim(added)
+		if last == nil {
+			break
 		}
+		c.remove(last)
+		c.evictions.Add(1)
+		evicted = append(evicted, eviction[K, V]{key: last.key, value: last.value, reason: EvictionCapacity})
 	}
 
-	return nil
+	return evicted
+}
+
+// remove must be called with the lock held
+func (c *InMemoryCache[K, V]) remove(e *entry[K, V]) {
+	c.policy.remove(e)
+	c.unschedule(e)
+	delete(c.items, e.key)
+	c.totalCost -= e.cost
 }
 
 // Get returns the value associated with the given key.
-// If the key is expired, returns ErrExpired and ErrNotFound if the key is not found
-func (c *InMemoryCache) Get(key interface{}) (interface{}, error) {
+// If the key is expired, returns ErrExpired and ErrNotFound if the key is not found.
+// In sliding mode a successful Get also renews the TTL of the item
+func (c *InMemoryCache[K, V]) Get(key K) (V, error) {
+	var zero V
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	elem, ok := c.items[key]
-	if !ok {
-		return nil, ErrNotFound
+	e, ok := c.items[key]
+	if !ok || c.isNil(e.value) {
+		c.misses.Add(1)
+		c.policy.miss(key)
+		return zero, ErrNotFound
 	}
 
-	itm, ok := elem.Value.(*item)
-	if !ok {
-		return nil, ErrUnexpectedType
+	now := time.Now()
+	if e.expired(now) {
+		c.misses.Add(1)
+		c.policy.miss(key)
+		return zero, ErrExpired
 	}
 
-	if itm.value == nil {
-		return nil, ErrNotFound
+	if c.sliding {
+		e.touch(now)
+		c.schedule(e)
 	}
+	c.policy.access(e)
+	c.hits.Add(1)
+
+	return e.value, nil
+}
 
-	if time.Now().After(itm.expiredAt) {
-		return nil, ErrExpired
+// Delete a key from the cache, a load of the key in flight returns its value without caching it.
+// If the key is not found, return ErrNotFound
+func (c *InMemoryCache[K, V]) Delete(key K) error {
+	c.invalidateLoad(key)
+
+	c.mu.Lock()
+	e, ok := c.items[key]
+	if !ok {
+		c.mu.Unlock()
+		return ErrNotFound
 	}
+	c.remove(e)
+	hooks := c.hooks
+	c.mu.Unlock()
 
-	c.list.MoveToFront(elem)
+	notify(hooks, eviction[K, V]{key: key, value: e.value, reason: EvictionDeleted})
 
-	return itm.value, nil
+	return nil
 }
 
-// Delete a key from the cache.
-// If the key is not found, return ErrNotFound
-func (c *InMemoryCache) Delete(key interface{}) error {
+// Peek returns the value and its expiration time without updating
+// the LRU order, the TTL or the hit and miss counters.
+// Expired items are returned too, it is up to the caller to check expiredAt,
+// which is zero for items that never expire
+func (c *InMemoryCache[K, V]) Peek(key K) (value V, expiredAt time.Time, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return value, time.Time{}, ErrNotFound
+	}
+
+	return e.value, e.expiredAt, nil
+}
+
+// Purge removes all items from the cache, loads in flight return their values without caching them
+func (c *InMemoryCache[K, V]) Purge() {
+	c.invalidateLoads()
+
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	if len(c.hooks) > 0 {
+		evicted = make([]eviction[K, V], 0, len(c.items))
+		c.policy.each(func(e *entry[K, V]) {
+			evicted = append(evicted, eviction[K, V]{key: e.key, value: e.value, reason: EvictionDeleted})
+		})
+	}
+	c.items = make(map[K]*entry[K, V])
+	c.policy.reset()
+	clear(c.expiry)
+	c.expiry = c.expiry[:0]
+	c.totalCost = 0
+	hooks := c.hooks
+	c.mu.Unlock()
+
+	notify(hooks, evicted...)
+}
+
+// OnEvict registers a hook called for every item that leaves the cache,
+// with the reason it was removed. Hooks run after the lock is released,
+// so they may do I/O or call the cache again, but they delay the caller
+// of the operation that removed the item
+func (c *InMemoryCache[K, V]) OnEvict(hook EvictFunc[K, V]) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if elem, ok := c.items[key]; ok {
-		c.list.Remove(elem)
-		delete(c.items, key)
-		return nil
+	c.hooks = append(c.hooks, hook)
+}
+
+// Len returns the number of items in the cache, including expired ones not cleaned up yet
+func (c *InMemoryCache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.items)
+}
+
+// Keys returns the keys of the cache, including expired ones not cleaned up yet.
+// For the LRU policy the most recently used key comes first
+func (c *InMemoryCache[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]K, 0, len(c.items))
+	c.policy.each(func(e *entry[K, V]) {
+		keys = append(keys, e.key)
+	})
+
+	return keys
+}
+
+// Range calls fn for every item in the order of Keys until fn returns false.
+// Like Peek it does not update the LRU order, the TTL or the counters.
+// fn is called on a copy taken under the lock, so it may call the cache,
+// but it may see items that have been removed since
+func (c *InMemoryCache[K, V]) Range(fn func(key K, value V, expiredAt time.Time) bool) {
+	c.mu.RLock()
+	items := make([]entry[K, V], 0, len(c.items))
+	c.policy.each(func(e *entry[K, V]) {
+		items = append(items, entry[K, V]{key: e.key, value: e.value, expiredAt: e.expiredAt})
+	})
+	c.mu.RUnlock()
+
+	for _, e := range items {
+		if !fn(e.key, e.value, e.expiredAt) {
+			return
+		}
+	}
+}
+
+// Resize changes the max number of items, zero removes the limit.
+// Shrinking evicts least recently used items until the cache fits, it returns their number
+func (c *InMemoryCache[K, V]) Resize(cap int) int {
+	c.mu.Lock()
+	c.cap = max(cap, 0)
+	c.policy.resize(c.cap)
+	evicted := c.evict(nil, nil)
+	hooks := c.hooks
+	c.mu.Unlock()
+
+	notify(hooks, evicted...)
+
+	return len(evicted)
+}
+
+// TTLRemaining returns how long the item has left to live, NoExpiry for items that never expire.
+// Like Peek it does not update the LRU order, the TTL or the counters.
+// It returns ErrNotFound if the key is not found and ErrExpired if the item has expired
+func (c *InMemoryCache[K, V]) TTLRemaining(key K) (time.Duration, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if e.expiredAt.IsZero() {
+		return NoExpiry, nil
+	}
+
+	remaining := time.Until(e.expiredAt)
+	if remaining < 0 {
+		return 0, ErrExpired
+	}
+
+	return remaining, nil
+}
+
+// SetMaxCost bounds the cache by the total cost of its values instead of, or in addition to,
+// the number of items. Costs of the cached values are recalculated and least recently used items
+// are evicted until the budget is met. A maxCost of zero or a nil cost removes the budget
+//   - maxCost: budget in the units returned by cost, e.g. bytes
+//   - cost: estimates the cost of a value, it is called under the lock and should be cheap
+func (c *InMemoryCache[K, V]) SetMaxCost(maxCost int64, cost CostFunc[V]) {
+	c.mu.Lock()
+	if maxCost <= 0 || cost == nil {
+		maxCost, cost = 0, nil
 	}
+	c.maxCost = maxCost
+	c.cost = cost
+	c.totalCost = 0
+	for _, e := range c.items {
+		e.cost = 0
+		if cost != nil {
+			c.setCost(e, cost(e.value))
+		}
+	}
+	evicted := c.evict(nil, nil)
+	hooks := c.hooks
+	c.mu.Unlock()
 
-	return ErrNotFound
+	notify(hooks, evicted...)
+}
+
+// Stats returns the current size and the counters collected since the cache was created
+func (c *InMemoryCache[K, V]) Stats() Stats {
+	c.mu.RLock()
+	length, capacity, cost, maxCost := len(c.items), c.cap, c.totalCost, c.maxCost
+	c.mu.RUnlock()
+
+	return Stats{
+		Len:         length,
+		Capacity:    capacity,
+		Cost:        cost,
+		MaxCost:     maxCost,
+		Hits:        c.hits.Load(),
+		StaleHits:   c.staleHits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
 }
 
 // StartCleanup launches a background goroutine that periodically
-// removes expired items from the cache.
+// removes expired items from the cache. Only the expired items are visited,
+// and the lock is released after every cleanupChunk of them.
 //   - interval: cleaning interval for old items
-func (c *InMemoryCache) StartCleanup(ctx context.Context, interval time.Duration) {
+func (c *InMemoryCache[K, V]) StartCleanup(ctx context.Context, interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
@@ -136,18 +426,7 @@ func (c *InMemoryCache) StartCleanup(ctx context.Context, interval time.Duration
 		for {
 			select {
 			case <-ticker.C:
-				c.mu.Lock()
-				for key, elem := range c.items {
-					itm, ok := elem.Value.(*item)
-					if !ok {
-						continue
-					}
-					if time.Now().After(itm.expiredAt) {
-						c.list.Remove(elem)
-						delete(c.items, key)
-					}
-				}
-				c.mu.Unlock()
+				c.removeExpired()
 
 			case <-ctx.Done():
 				return
@@ -155,3 +434,7 @@ func (c *InMemoryCache) StartCleanup(ctx context.Context, interval time.Duration
 		}
 	}()
 }
+
+func (c *InMemoryCache[K, V]) isNil(value V) bool {
+	return c.nilable && any(value) == nil
+}
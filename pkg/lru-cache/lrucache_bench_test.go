@@ -0,0 +1,219 @@
+package lrucache
+
+import (
+	"context"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type benchOrder struct {
+	id    string
+	items []int
+}
+
+const benchKeys = 1024
+
+func benchKeySet() []string {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "order-" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkInMemoryCache_Get(b *testing.B) {
+	keys := benchKeySet()
+
+	b.Run("typed", func(b *testing.B) {
+		cache := NewInMemoryCache[string, *benchOrder](benchKeys, time.Hour)
+		for _, k := range keys {
+			cache.Set(k, &benchOrder{id: k})
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			order, _ := cache.Get(keys[i%benchKeys])
+			_ = order.id
+		}
+	})
+
+	b.Run("any", func(b *testing.B) {
+		cache := New(benchKeys, time.Hour)
+		for _, k := range keys {
+			cache.Set(k, &benchOrder{id: k})
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			val, _ := cache.Get(keys[i%benchKeys])
+			order, _ := val.(*benchOrder)
+			_ = order.id
+		}
+	})
+}
+
+func BenchmarkInMemoryCache_Set(b *testing.B) {
+	keys := benchKeySet()
+	order := &benchOrder{id: "order"}
+
+	b.Run("typed/update", func(b *testing.B) {
+		cache := NewInMemoryCache[string, *benchOrder](benchKeys, time.Hour)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			cache.Set(keys[i%benchKeys], order)
+		}
+	})
+
+	b.Run("any/update", func(b *testing.B) {
+		cache := New(benchKeys, time.Hour)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			cache.Set(keys[i%benchKeys], order)
+		}
+	})
+
+	b.Run("typed/evict", func(b *testing.B) {
+		cache := NewInMemoryCache[int, *benchOrder](benchKeys, time.Hour)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			cache.Set(i, order)
+		}
+	})
+
+	b.Run("any/evict", func(b *testing.B) {
+		cache := New(benchKeys, time.Hour)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := range b.N {
+			cache.Set(i, order)
+		}
+	})
+}
+
+// BenchmarkCache_Parallel compares the single-lock cache with the sharded one
+// under concurrent load, 90% reads and 10% writes
+func BenchmarkCache_Parallel(b *testing.B) {
+	keys := benchKeySet()
+
+	caches := []struct {
+		name  string
+		cache Cache[string, *benchOrder]
+	}{
+		{name: "single", cache: NewInMemoryCache[string, *benchOrder](benchKeys, time.Hour)},
+		{name: "sharded-4", cache: NewShardedCache[string, *benchOrder](4, benchKeys, time.Hour)},
+		{name: "sharded-16", cache: NewShardedCache[string, *benchOrder](16, benchKeys, time.Hour)},
+		{name: "sharded-64", cache: NewShardedCache[string, *benchOrder](64, benchKeys, time.Hour)},
+	}
+
+	for _, bc := range caches {
+		for _, k := range keys {
+			bc.cache.Set(k, &benchOrder{id: k})
+		}
+
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				// start every goroutine at its own key, so they do not walk the shards in lockstep
+				i := rand.IntN(benchKeys)
+				for pb.Next() {
+					key := keys[i%benchKeys]
+					if i%10 == 0 {
+						bc.cache.Set(key, &benchOrder{id: key})
+					} else {
+						bc.cache.Get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkInMemoryCache_Policy(b *testing.B) {
+	keys := benchKeySet()
+
+	for _, p := range Policies {
+		b.Run(string(p), func(b *testing.B) {
+			cache := NewInMemoryCache[string, *benchOrder](benchKeys/2, time.Hour, WithPolicy(p))
+			order := &benchOrder{id: "order"}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				key := keys[i%benchKeys]
+				if _, err := cache.Get(key); err != nil {
+					cache.Set(key, order)
+				}
+			}
+		})
+	}
+}
+
+// benchExpiryCache returns a cache of n long-lived items, the ones cleanup has to skip
+func benchExpiryCache(n int) *InMemoryCache[int, *benchOrder] {
+	cache := NewInMemoryCache[int, *benchOrder](0, time.Hour)
+	order := &benchOrder{id: "order"}
+	for i := range n {
+		cache.Set(i, order)
+	}
+	return cache
+}
+
+// BenchmarkInMemoryCache_RemoveExpired measures one cleanup pass over a large cache
+// where only a few items have expired since the previous pass
+func BenchmarkInMemoryCache_RemoveExpired(b *testing.B) {
+	const expiring = 100
+
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			cache := benchExpiryCache(n)
+			order := &benchOrder{id: "order"}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				b.StopTimer()
+				for k := range expiring {
+					cache.SetWithTTL(n+k, order, time.Nanosecond)
+				}
+				b.StartTimer()
+				cache.removeExpired()
+			}
+		})
+	}
+}
+
+// BenchmarkInMemoryCache_GetDuringCleanup measures the latency of Get
+// while cleanup passes run back to back, the tail shows how long readers wait for the lock
+func BenchmarkInMemoryCache_GetDuringCleanup(b *testing.B) {
+	const n = 200_000
+
+	cache := benchExpiryCache(n)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			cache.removeExpired()
+			runtime.Gosched()
+		}
+	}()
+
+	latencies := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := range b.N {
+		start := time.Now()
+		cache.Get(i % n)
+		latencies = append(latencies, time.Since(start))
+	}
+	b.StopTimer()
+
+	slices.Sort(latencies)
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+	b.ReportMetric(float64(latencies[len(latencies)*999/1000].Nanoseconds()), "p99.9-ns")
+	b.ReportMetric(float64(latencies[len(latencies)-1].Nanoseconds()), "max-ns")
+}
@@ -2,6 +2,7 @@ package lrucache
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -131,7 +132,7 @@ func TestInMemoryCache_Set(t *testing.T) {
 			} else {
 				require.NoError(t, err, "Set() returned unexpected error")
 			}
-			require.Equal(t, tt.wantLen, cache.list.Len(), "cache length after Set() mismatch")
+			require.Equal(t, tt.wantLen, cache.Len(), "cache length after Set() mismatch")
 		})
 	}
 }
@@ -141,7 +142,7 @@ func TestInMemoryCache_Get(t *testing.T) {
 		name        string
 		capacity    int
 		ttl         time.Duration
-		setup       func(*InMemoryCache)
+		setup       func(*InMemoryCache[any, any])
 		key         interface{}
 		wantValue   interface{}
 		wantErr     error
@@ -151,7 +152,7 @@ func TestInMemoryCache_Get(t *testing.T) {
 			name:     "get existing item",
 			capacity: 10,
 			ttl:      time.Minute,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 			},
 			key:         "key1",
@@ -163,7 +164,7 @@ func TestInMemoryCache_Get(t *testing.T) {
 			name:     "get non-existing item",
 			capacity: 10,
 			ttl:      time.Minute,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 			},
 			key:         "key2",
@@ -175,7 +176,7 @@ func TestInMemoryCache_Get(t *testing.T) {
 			name:     "get expired item",
 			capacity: 10,
 			ttl:      time.Millisecond,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 				time.Sleep(2 * time.Millisecond)
 			},
@@ -188,7 +189,7 @@ func TestInMemoryCache_Get(t *testing.T) {
 			name:     "get updates LRU order",
 			capacity: 2,
 			ttl:      time.Minute,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 				c.Set("key2", "value2")
 				c.Get("key1")
@@ -203,7 +204,7 @@ func TestInMemoryCache_Get(t *testing.T) {
 			name:     "get different types",
 			capacity: 10,
 			ttl:      time.Minute,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set(123, "numeric key")
 				c.Set("string", 456)
 			},
@@ -236,7 +237,7 @@ func TestInMemoryCache_Delete(t *testing.T) {
 		name        string
 		capacity    int
 		ttl         time.Duration
-		setup       func(*InMemoryCache)
+		setup       func(*InMemoryCache[any, any])
 		deleteKey   interface{}
 		wantErr     error
 		wantLen     int
@@ -246,7 +247,7 @@ func TestInMemoryCache_Delete(t *testing.T) {
 			name:     "delete existing item",
 			capacity: 10,
 			ttl:      time.Minute,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 				c.Set("key2", "value2")
 			},
@@ -259,7 +260,7 @@ func TestInMemoryCache_Delete(t *testing.T) {
 			name:     "delete non-existing item",
 			capacity: 10,
 			ttl:      time.Minute,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 			},
 			deleteKey:   "key2",
@@ -271,7 +272,7 @@ func TestInMemoryCache_Delete(t *testing.T) {
 			name:        "delete from empty cache",
 			capacity:    10,
 			ttl:         time.Minute,
-			setup:       func(c *InMemoryCache) {},
+			setup:       func(c *InMemoryCache[any, any]) {},
 			deleteKey:   "key1",
 			wantErr:     ErrNotFound,
 			wantLen:     0,
@@ -281,7 +282,7 @@ func TestInMemoryCache_Delete(t *testing.T) {
 			name:     "delete all items",
 			capacity: 10,
 			ttl:      time.Minute,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 				c.Delete("key1")
 			},
@@ -302,7 +303,7 @@ func TestInMemoryCache_Delete(t *testing.T) {
 			err := cache.Delete(tt.deleteKey)
 
 			require.ErrorIs(t, err, tt.wantErr, "unexpected error from Delete()")
-			require.Equal(t, tt.wantLen, cache.list.Len(), "cache length after delete mismatch")
+			require.Equal(t, tt.wantLen, cache.Len(), "cache length after delete mismatch")
 		})
 	}
 }
@@ -313,7 +314,7 @@ func TestInMemoryCache_StartCleanup(t *testing.T) {
 		capacity        int
 		ttl             time.Duration
 		cleanupInterval time.Duration
-		setup           func(*InMemoryCache)
+		setup           func(*InMemoryCache[any, any])
 		waitTime        time.Duration
 		wantLenAfter    int
 		description     string
@@ -323,7 +324,7 @@ func TestInMemoryCache_StartCleanup(t *testing.T) {
 			capacity:        10,
 			ttl:             50 * time.Millisecond,
 			cleanupInterval: 30 * time.Millisecond,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 				c.Set("key2", "value2")
 			},
@@ -336,7 +337,7 @@ func TestInMemoryCache_StartCleanup(t *testing.T) {
 			capacity:        10,
 			ttl:             time.Second,
 			cleanupInterval: 20 * time.Millisecond,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 				c.Set("key2", "value2")
 			},
@@ -349,7 +350,7 @@ func TestInMemoryCache_StartCleanup(t *testing.T) {
 			capacity:        10,
 			ttl:             time.Minute,
 			cleanupInterval: 10 * time.Millisecond,
-			setup: func(c *InMemoryCache) {
+			setup: func(c *InMemoryCache[any, any]) {
 				c.Set("key1", "value1")
 			},
 			waitTime:     20 * time.Millisecond,
@@ -373,7 +374,7 @@ func TestInMemoryCache_StartCleanup(t *testing.T) {
 			cancel()
 
 			cache.mu.RLock()
-			gotLen := cache.list.Len()
+			gotLen := cache.Len()
 			cache.mu.RUnlock()
 
 			require.Equal(t, tt.wantLenAfter, gotLen, "cache length after cleanup mismatch")
@@ -413,10 +414,10 @@ func TestInMemoryCache_New(t *testing.T) {
 			cache := New(tt.capacity, tt.ttl)
 
 			require.NotNil(t, cache, "New() returned nil")
-			require.Equal(t, tt.capacity, cache.cap, "cache capacity mismatch")
+			require.Equal(t, tt.capacity, cache.Stats().Capacity, "cache capacity mismatch")
 			require.Equal(t, cache.TTL, tt.ttl, "cache TTL mismatch")
-			require.NotNil(t, cache.items, "cache.items should not be nil")
-			require.NotNil(t, cache.list, "cache.list should not be nil")
+			require.Zero(t, cache.Len(), "new cache should be empty")
+			require.Empty(t, cache.Keys(), "new cache should have no keys")
 		})
 	}
 }
@@ -480,7 +481,533 @@ func TestInMemoryCache_Concurrency(t *testing.T) {
 				<-done
 			}
 
-			assert.LessOrEqual(t, len(cache.items), tt.capacity)
+			assert.LessOrEqual(t, cache.Len(), tt.capacity)
 		})
 	}
 }
+
+func TestInMemoryCache_Peek(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		setup       func(*InMemoryCache[any, any])
+		key         interface{}
+		wantValue   interface{}
+		wantErr     error
+		wantExpired bool
+	}{
+		{
+			name: "peek existing item",
+			ttl:  time.Minute,
+			setup: func(c *InMemoryCache[any, any]) {
+				c.Set("key1", "value1")
+			},
+			key:       "key1",
+			wantValue: "value1",
+		},
+		{
+			name:    "peek non-existing item",
+			ttl:     time.Minute,
+			setup:   func(c *InMemoryCache[any, any]) {},
+			key:     "key1",
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "peek expired item",
+			ttl:  time.Millisecond,
+			setup: func(c *InMemoryCache[any, any]) {
+				c.Set("key1", "value1")
+				time.Sleep(2 * time.Millisecond)
+			},
+			key:         "key1",
+			wantValue:   "value1",
+			wantExpired: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := New(10, tt.ttl)
+			tt.setup(cache)
+
+			got, expiredAt, err := cache.Peek(tt.key)
+
+			require.ErrorIs(t, err, tt.wantErr)
+			if err == nil {
+				require.Equal(t, tt.wantValue, got)
+				require.Equal(t, tt.wantExpired, time.Now().After(expiredAt))
+			}
+			stats := cache.Stats()
+			require.Zero(t, stats.Hits+stats.Misses, "Peek() should not count hits or misses")
+		})
+	}
+}
+
+func TestInMemoryCache_PeekKeepsLRUOrder(t *testing.T) {
+	cache := New(2, time.Minute)
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Peek("key1")
+	cache.Set("key3", "value3")
+
+	_, _, err := cache.Peek("key1")
+	require.ErrorIs(t, err, ErrNotFound, "peeked item should still be evicted first")
+}
+
+func TestInMemoryCache_Purge(t *testing.T) {
+	cache := New(10, time.Minute)
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+
+	cache.Purge()
+
+	require.Equal(t, 0, cache.Len())
+	_, err := cache.Get("key1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, cache.Set("key3", "value3"))
+	require.Equal(t, 1, cache.Len())
+}
+
+func TestInMemoryCache_Stats(t *testing.T) {
+	cache := New(2, time.Minute)
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Set("key3", "value3")
+	cache.Get("key3")
+	cache.Get("key1")
+
+	require.Equal(t, Stats{
+		Len:       2,
+		Capacity:  2,
+		Hits:      1,
+		Misses:    1,
+		Evictions: 1,
+	}, cache.Stats())
+}
+
+func TestInMemoryCache_Typed(t *testing.T) {
+	type order struct{ id string }
+
+	cache := NewInMemoryCache[string, *order](2, time.Minute)
+	require.NoError(t, cache.Set("key1", &order{id: "1"}))
+	require.NoError(t, cache.Set("key2", &order{id: "2"}))
+
+	got, err := cache.Get("key1")
+	require.NoError(t, err)
+	require.Equal(t, "1", got.id)
+
+	require.NoError(t, cache.Set("key3", &order{id: "3"}))
+	_, err = cache.Get("key2")
+	require.ErrorIs(t, err, ErrNotFound, "least recently used item should be evicted")
+
+	got, err = cache.Get("missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Nil(t, got, "zero value should be returned on miss")
+}
+
+func TestInMemoryCache_SetWithTTL(t *testing.T) {
+	tests := []struct {
+		name           string
+		ttl            time.Duration
+		wait           time.Duration
+		wantErr        error
+		wantNoDeadline bool
+	}{
+		{
+			name:    "shorter than default expires",
+			ttl:     10 * time.Millisecond,
+			wait:    20 * time.Millisecond,
+			wantErr: ErrExpired,
+		},
+		{
+			name: "longer than default survives",
+			ttl:  time.Minute,
+			wait: 20 * time.Millisecond,
+		},
+		{
+			name:           "no expiry survives",
+			ttl:            NoExpiry,
+			wait:           20 * time.Millisecond,
+			wantNoDeadline: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, string](10, 10*time.Millisecond)
+			require.NoError(t, cache.SetWithTTL("key", "value", tt.ttl))
+
+			_, expiredAt, err := cache.Peek("key")
+			require.NoError(t, err)
+			require.Equal(t, tt.wantNoDeadline, expiredAt.IsZero())
+
+			time.Sleep(tt.wait)
+			_, err = cache.Get("key")
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestInMemoryCache_NoExpirySurvivesCleanup(t *testing.T) {
+	cache := NewInMemoryCache[string, string](10, NoExpiry)
+	require.NoError(t, cache.Set("key", "value"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache.StartCleanup(ctx, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	got, err := cache.Get("key")
+	require.NoError(t, err)
+	require.Equal(t, "value", got)
+}
+
+func TestInMemoryCache_SlidingExpiration(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{
+			name:    "fixed deadline expires despite reads",
+			wantErr: ErrExpired,
+		},
+		{
+			name: "sliding deadline is renewed by reads",
+			opts: []Option{WithSlidingExpiration()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, string](10, 40*time.Millisecond, tt.opts...)
+			require.NoError(t, cache.Set("key", "value"))
+
+			for range 3 {
+				time.Sleep(20 * time.Millisecond)
+				cache.Get("key")
+			}
+
+			_, err := cache.Get("key")
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestInMemoryCache_PeekDoesNotSlide(t *testing.T) {
+	cache := NewInMemoryCache[string, string](10, time.Minute, WithSlidingExpiration())
+	require.NoError(t, cache.Set("key", "value"))
+	_, before, err := cache.Peek("key")
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	_, after, err := cache.Peek("key")
+	require.NoError(t, err)
+	require.Equal(t, before, after, "Peek() should not move the deadline")
+}
+
+func TestInMemoryCache_SetMaxCost(t *testing.T) {
+	cost := func(v string) int64 { return int64(len(v)) }
+
+	tests := []struct {
+		name      string
+		maxCost   int64
+		ops       func(c *InMemoryCache[string, string])
+		wantKeys  []string
+		wantCost  int64
+		wantEvict uint64
+	}{
+		{
+			name:    "evicts until budget is met",
+			maxCost: 10,
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "aaaa")
+				c.Set("key2", "bbbb")
+				c.Set("key3", "cccccccc")
+			},
+			wantKeys:  []string{"key3"},
+			wantCost:  8,
+			wantEvict: 2,
+		},
+		{
+			name:    "replace updates cost",
+			maxCost: 10,
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "aaaa")
+				c.Set("key2", "bbbb")
+				c.Set("key2", "bb")
+				c.Set("key3", "cccc")
+			},
+			wantKeys: []string{"key1", "key2", "key3"},
+			wantCost: 10,
+		},
+		{
+			name:    "item over budget is rejected",
+			maxCost: 3,
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "a")
+				require.ErrorIs(t, c.Set("key2", "bbbb"), ErrTooCostly)
+			},
+			wantKeys: []string{"key1"},
+			wantCost: 1,
+		},
+		{
+			name:    "replacement over budget drops the key",
+			maxCost: 3,
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "a")
+				c.Set("key2", "b")
+				require.ErrorIs(t, c.Set("key2", "bbbb"), ErrTooCostly)
+			},
+			wantKeys: []string{"key1"},
+			wantCost: 1,
+		},
+		{
+			name:    "delete releases cost",
+			maxCost: 10,
+			ops: func(c *InMemoryCache[string, string]) {
+				c.Set("key1", "aaaa")
+				c.Set("key2", "bbbb")
+				c.Delete("key1")
+			},
+			wantKeys: []string{"key2"},
+			wantCost: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, string](0, time.Minute)
+			cache.SetMaxCost(tt.maxCost, cost)
+
+			tt.ops(cache)
+
+			for _, key := range tt.wantKeys {
+				_, _, err := cache.Peek(key)
+				require.NoError(t, err, "key %s should be kept", key)
+			}
+			stats := cache.Stats()
+			require.Equal(t, len(tt.wantKeys), stats.Len)
+			require.Equal(t, tt.wantCost, stats.Cost)
+			require.Equal(t, tt.maxCost, stats.MaxCost)
+			require.Equal(t, tt.wantEvict, stats.Evictions)
+		})
+	}
+}
+
+func TestInMemoryCache_SetMaxCostShrinks(t *testing.T) {
+	cache := NewInMemoryCache[string, string](0, time.Minute)
+	cache.Set("key1", "aaaa")
+	cache.Set("key2", "bbbb")
+	cache.Set("key3", "cccc")
+
+	cache.SetMaxCost(8, func(v string) int64 { return int64(len(v)) })
+	_, _, err := cache.Peek("key1")
+	require.ErrorIs(t, err, ErrNotFound, "least recently used item should be evicted")
+	require.Equal(t, int64(8), cache.Stats().Cost)
+
+	cache.SetMaxCost(0, nil)
+	require.Equal(t, Stats{Len: 2, Evictions: 1}, cache.Stats())
+}
+
+func TestInMemoryCache_RemoveExpired(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		expiring    int
+		lasting     int
+		touch       bool
+		wantRemoved int
+	}{
+		{
+			name:        "more than one chunk",
+			expiring:    3*cleanupChunk + 1,
+			lasting:     10,
+			wantRemoved: 3*cleanupChunk + 1,
+		},
+		{
+			name:        "nothing expired",
+			lasting:     10,
+			wantRemoved: 0,
+		},
+		{
+			name:        "sliding read moves the deadline",
+			opts:        []Option{WithSlidingExpiration()},
+			expiring:    10,
+			touch:       true,
+			wantRemoved: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[int, int](0, 30*time.Millisecond, tt.opts...)
+			for i := range tt.expiring {
+				require.NoError(t, cache.Set(i, i))
+			}
+			for i := range tt.lasting {
+				require.NoError(t, cache.SetWithTTL(tt.expiring+i, i, NoExpiry))
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			if tt.touch {
+				for i := range tt.expiring {
+					_, err := cache.Get(i)
+					require.NoError(t, err)
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+
+			cache.removeExpired()
+			stats := cache.Stats()
+			require.Equal(t, uint64(tt.wantRemoved), stats.Expirations)
+			require.Equal(t, tt.expiring+tt.lasting-tt.wantRemoved, stats.Len)
+		})
+	}
+}
+
+func TestInMemoryCache_Keys(t *testing.T) {
+	cache := NewInMemoryCache[string, int](10, time.Minute)
+	cache.Set("key1", 1)
+	cache.Set("key2", 2)
+	cache.Set("key3", 3)
+	cache.Get("key1")
+
+	require.Equal(t, []string{"key1", "key3", "key2"}, cache.Keys(), "most recently used key should come first")
+}
+
+func TestInMemoryCache_Range(t *testing.T) {
+	cache := NewInMemoryCache[string, int](3, time.Minute)
+	cache.SetWithTTL("key1", 1, NoExpiry)
+	cache.Set("key2", 2)
+	cache.Set("key3", 3)
+
+	var keys []string
+	cache.Range(func(key string, value int, expiredAt time.Time) bool {
+		keys = append(keys, key)
+		if key == "key1" {
+			require.True(t, expiredAt.IsZero(), "item without expiration should have zero expiredAt")
+		} else {
+			require.False(t, expiredAt.IsZero())
+		}
+		// fn may call the cache
+		_, _, err := cache.Peek(key)
+		require.NoError(t, err)
+		return true
+	})
+	require.Equal(t, []string{"key3", "key2", "key1"}, keys)
+	require.Zero(t, cache.Stats().Hits+cache.Stats().Misses, "Range() should not count hits or misses")
+
+	cache.Set("key4", 4)
+	_, _, err := cache.Peek("key1")
+	require.ErrorIs(t, err, ErrNotFound, "Range() should not update the LRU order")
+
+	visited := 0
+	cache.Range(func(string, int, time.Time) bool {
+		visited++
+		return false
+	})
+	require.Equal(t, 1, visited, "Range() should stop when fn returns false")
+}
+
+func TestInMemoryCache_Resize(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  int
+		resize    int
+		wantEvict int
+		wantKeys  []string
+	}{
+		{
+			name:      "shrink evicts least recently used",
+			capacity:  4,
+			resize:    2,
+			wantEvict: 2,
+			wantKeys:  []string{"key4", "key3"},
+		},
+		{
+			name:      "grow keeps items",
+			capacity:  4,
+			resize:    8,
+			wantEvict: 0,
+			wantKeys:  []string{"key4", "key3", "key2", "key1"},
+		},
+		{
+			name:      "zero removes the limit",
+			capacity:  4,
+			resize:    0,
+			wantEvict: 0,
+			wantKeys:  []string{"key4", "key3", "key2", "key1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewInMemoryCache[string, int](tt.capacity, time.Minute)
+			var reasons []EvictionReason
+			cache.OnEvict(func(_ string, _ int, reason EvictionReason) {
+				reasons = append(reasons, reason)
+			})
+			for i := 1; i <= 4; i++ {
+				cache.Set("key"+strconv.Itoa(i), i)
+			}
+
+			require.Equal(t, tt.wantEvict, cache.Resize(tt.resize))
+			require.Equal(t, tt.wantKeys, cache.Keys())
+			require.Len(t, reasons, tt.wantEvict)
+			for _, reason := range reasons {
+				require.Equal(t, EvictionCapacity, reason)
+			}
+
+			stats := cache.Stats()
+			require.Equal(t, tt.resize, stats.Capacity)
+			require.Equal(t, uint64(tt.wantEvict), stats.Evictions)
+
+			for i := 5; i <= 12; i++ {
+				cache.Set("key"+strconv.Itoa(i), i)
+			}
+			if tt.resize > 0 {
+				require.Equal(t, tt.resize, cache.Len(), "new capacity should bound later sets")
+			} else {
+				require.Equal(t, 12, cache.Len())
+			}
+		})
+	}
+}
+
+func TestInMemoryCache_TTLRemaining(t *testing.T) {
+	cache := NewInMemoryCache[string, int](10, time.Minute)
+	cache.Set("default", 1)
+	cache.SetWithTTL("lasting", 2, NoExpiry)
+	cache.SetWithTTL("negative", 4, -time.Minute)
+	cache.SetWithTTL("expired", 3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		name    string
+		key     string
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "default TTL", key: "default", want: time.Minute},
+		{name: "no expiry", key: "lasting", want: NoExpiry},
+		{name: "negative TTL", key: "negative", want: NoExpiry},
+		{name: "expired", key: "expired", wantErr: ErrExpired},
+		{name: "missing", key: "missing", wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cache.TTLRemaining(tt.key)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.InDelta(t, tt.want, got, float64(time.Second))
+		})
+	}
+
+	require.Zero(t, cache.Stats().Hits+cache.Stats().Misses, "TTLRemaining() should not count hits or misses")
+}
@@ -0,0 +1,51 @@
+package lrucache
+
+import "time"
+
+// Option configures optional cache behaviour
+type Option func(*options)
+
+type options struct {
+	sliding     bool
+	policy      Policy
+	loadTimeout time.Duration
+	staleGrace  time.Duration
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithSlidingExpiration makes every successful Get renew the TTL of the item,
+// so frequently read items stay cached while idle ones expire
+func WithSlidingExpiration() Option {
+	return func(o *options) {
+		o.sliding = true
+	}
+}
+
+// WithPolicy sets the eviction policy, PolicyLRU is used by default and for unknown policies
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithLoadTimeout bounds the loads of GetOrLoad, no timeout is set by default
+func WithLoadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.loadTimeout = d
+	}
+}
+
+// WithStaleWhileRevalidate makes GetOrLoad serve items expired less than grace ago
+// while reloading them in the background, and keeps such items from being cleaned up
+func WithStaleWhileRevalidate(grace time.Duration) Option {
+	return func(o *options) {
+		o.staleGrace = grace
+	}
+}
@@ -0,0 +1,89 @@
+package lrucache
+
+import "fmt"
+
+// Policy selects which item is evicted when the cache is full
+type Policy string
+
+const (
+	// PolicyLRU evicts the least recently used item
+	PolicyLRU Policy = "lru"
+	// PolicyLFU evicts the least frequently used item, the least recently used one among equals.
+	// Counts never decay, so items that were popular once stay cached for long
+	PolicyLFU Policy = "lfu"
+	// PolicyTinyLFU is W-TinyLFU: new items go to a small LRU window and are admitted
+	// to the main segmented LRU only if a count-min sketch estimates them to be
+	// more frequent than the item they would replace, so one-off scans do not flush hot items
+	PolicyTinyLFU Policy = "tinylfu"
+)
+
+// Policies lists the supported policies
+var Policies = []Policy{PolicyLRU, PolicyLFU, PolicyTinyLFU}
+
+// ParsePolicy parses a policy name, an empty name is PolicyLRU
+func ParsePolicy(name string) (Policy, error) {
+	if name == "" {
+		return PolicyLRU, nil
+	}
+	for _, p := range Policies {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownPolicy, name)
+}
+
+// policy keeps the entries in the order they should be evicted in.
+// All methods are called with the cache lock held
+type policy[K comparable, V any] interface {
+	// add is called for an entry that was just inserted
+	add(e *entry[K, V])
+	// access is called on a hit and when the value of an entry is replaced
+	access(e *entry[K, V])
+	// miss is called when a key is not found
+	miss(key K)
+	// remove is called for an entry that is leaving the cache
+	remove(e *entry[K, V])
+	// selectVictim returns the entry to evict next, or nil if there are none. The cache removes
+	// the entry right away, so a policy may settle admission while selecting, as W-TinyLFU
+	// moves a window candidate to the main segment. added is the entry inserted by the operation
+	// that evicts, nil if there is none, and should be picked only when no other entry is left
+	selectVictim(added *entry[K, V]) *entry[K, V]
+	// each calls fn for every entry, the most valuable first where the policy has such an order
+	each(fn func(e *entry[K, V]))
+	// reset drops all entries
+	reset()
+	// resize is called when the capacity of the cache changes, before it evicts down to it
+	resize(capacity int)
+}
+
+func newPolicy[K comparable, V any](p Policy, capacity int) policy[K, V] {
+	switch p {
+	case PolicyLFU:
+		return newLFUPolicy[K, V]()
+	case PolicyTinyLFU:
+		return newTinyLFUPolicy[K, V](capacity)
+	default:
+		return newLRUPolicy[K, V]()
+	}
+}
+
+type lruPolicy[K comparable, V any] struct {
+	list *lruList[K, V]
+}
+
+func newLRUPolicy[K comparable, V any]() *lruPolicy[K, V] {
+	return &lruPolicy[K, V]{list: newLRUList[K, V]()}
+}
+
+func (p *lruPolicy[K, V]) add(e *entry[K, V])                     { p.list.PushFront(e) }
+func (p *lruPolicy[K, V]) access(e *entry[K, V])                  { p.list.MoveToFront(e) }
+func (p *lruPolicy[K, V]) miss(K)                                 {}
+func (p *lruPolicy[K, V]) remove(e *entry[K, V])                  { p.list.Remove(e) }
+func (p *lruPolicy[K, V]) selectVictim(*entry[K, V]) *entry[K, V] { return p.list.Back() }
+func (p *lruPolicy[K, V]) reset()                                 { p.list.Init() }
+func (p *lruPolicy[K, V]) resize(int)                             {}
+
+func (p *lruPolicy[K, V]) each(fn func(e *entry[K, V])) {
+	p.list.each(fn)
+}
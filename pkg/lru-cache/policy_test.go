@@ -0,0 +1,219 @@
+package lrucache
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Policy
+		wantErr error
+	}{
+		{name: "", want: PolicyLRU},
+		{name: "lru", want: PolicyLRU},
+		{name: "lfu", want: PolicyLFU},
+		{name: "tinylfu", want: PolicyTinyLFU},
+		{name: "LRU", wantErr: ErrUnknownPolicy},
+		{name: "fifo", wantErr: ErrUnknownPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(tt.name)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInMemoryCache_PolicyLFU(t *testing.T) {
+	cache := NewInMemoryCache[string, int](3, time.Minute, WithPolicy(PolicyLFU))
+	cache.Set("key1", 1)
+	cache.Set("key2", 2)
+	cache.Set("key3", 3)
+	for range 3 {
+		cache.Get("key1")
+		cache.Get("key3")
+	}
+	cache.Get("key2")
+
+	// key2 is the most recently used, but the least frequently used one
+	cache.Set("key4", 4)
+	_, err := cache.Get("key2")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// among equally used items the least recently used one is evicted
+	cache.Set("key5", 5)
+	_, err = cache.Get("key4")
+	require.ErrorIs(t, err, ErrNotFound)
+	for _, key := range []string{"key1", "key3", "key5"} {
+		_, err = cache.Get(key)
+		require.NoError(t, err, "key %s should be kept", key)
+	}
+}
+
+func TestInMemoryCache_PolicyLFUKeys(t *testing.T) {
+	cache := NewInMemoryCache[string, int](4, time.Minute, WithPolicy(PolicyLFU))
+	cache.Set("key1", 1)
+	cache.Set("key2", 2)
+	cache.Set("key3", 3)
+	cache.Set("key4", 4)
+	for range 3 {
+		cache.Get("key3")
+	}
+	cache.Get("key1")
+
+	// the buckets live in a map, the order must not depend on its iteration
+	for range 20 {
+		require.Equal(t, []string{"key3", "key1", "key4", "key2"}, cache.Keys())
+	}
+}
+
+// the last added item is spared only by the eviction of its own insertion
+func TestInMemoryCache_PolicyLFUResize(t *testing.T) {
+	cache := NewInMemoryCache[string, int](2, time.Minute, WithPolicy(PolicyLFU))
+	cache.Set("key1", 1)
+	cache.Set("key2", 2)
+	cache.Get("key1")
+
+	require.Equal(t, 1, cache.Resize(1))
+	_, err := cache.Get("key2")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = cache.Get("key1")
+	require.NoError(t, err)
+}
+
+func TestInMemoryCache_PolicyScanResistance(t *testing.T) {
+	tests := []struct {
+		policy      Policy
+		wantHotKept bool
+	}{
+		{policy: PolicyLRU, wantHotKept: false},
+		{policy: PolicyLFU, wantHotKept: true},
+		{policy: PolicyTinyLFU, wantHotKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			cache := NewInMemoryCache[string, int](100, time.Minute, WithPolicy(tt.policy))
+			for range 5 {
+				for i := range 50 {
+					key := "hot" + strconv.Itoa(i)
+					if _, err := cache.Get(key); err != nil {
+						cache.Set(key, i)
+					}
+				}
+			}
+
+			for i := range 1000 {
+				key := "scan" + strconv.Itoa(i)
+				if _, _, err := cache.Peek(key); err != nil {
+					cache.Get(key)
+					cache.Set(key, i)
+				}
+			}
+
+			kept := 0
+			for i := range 50 {
+				if _, _, err := cache.Peek("hot" + strconv.Itoa(i)); err == nil {
+					kept++
+				}
+			}
+			if tt.wantHotKept {
+				require.GreaterOrEqual(t, kept, 45, "hot items should survive a scan")
+			} else {
+				require.Zero(t, kept, "hot items should be flushed by a scan")
+			}
+		})
+	}
+}
+
+// TestInMemoryCache_PolicyInvariants runs random operations against every policy
+// and checks that the policy and the expiry heap track exactly the items of the cache
+func TestInMemoryCache_PolicyInvariants(t *testing.T) {
+	for _, p := range Policies {
+		t.Run(string(p), func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			cache := NewInMemoryCache[int, int](64, time.Minute, WithPolicy(p))
+
+			for i := range 20000 {
+				key := r.IntN(256)
+				switch r.IntN(10) {
+				case 0:
+					cache.Delete(key)
+				case 1:
+					cache.SetWithTTL(key, i, time.Nanosecond)
+				case 2:
+					cache.removeExpired()
+				case 3, 4, 5:
+					cache.Set(key, i)
+				case 6:
+					if r.IntN(100) == 0 {
+						cache.Resize(16 + r.IntN(49))
+					}
+				default:
+					cache.Get(key)
+				}
+				if i%1000 == 0 {
+					cache.Purge()
+				}
+
+				require.LessOrEqual(t, cache.Len(), 64)
+			}
+
+			seen := 0
+			cache.policy.each(func(e *entry[int, int]) {
+				require.Same(t, cache.items[e.key], e)
+				seen++
+			})
+			require.Equal(t, cache.Len(), seen)
+
+			for i, e := range cache.expiry {
+				require.Equal(t, i, e.heapIndex)
+				require.Same(t, cache.items[e.key], e, "expiry heap should only hold cached items")
+			}
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	trace := skewedTraceWithScans(100000, 10000)
+
+	results := make(map[Policy]SimulationResult, len(Policies))
+	for _, p := range Policies {
+		results[p] = Simulate(p, 500, trace)
+		require.Equal(t, len(trace), results[p].Requests)
+		require.Positive(t, results[p].Hits)
+	}
+
+	require.Greater(t, results[PolicyTinyLFU].HitRatio(), results[PolicyLRU].HitRatio(),
+		"W-TinyLFU should beat LRU on a skewed trace with scans")
+}
+
+// skewedTraceWithScans returns a Zipf distributed trace over keys
+// interleaved with one-off scans of keys that are never requested again
+func skewedTraceWithScans(n, keys int) []string {
+	r := rand.New(rand.NewPCG(1, 2))
+	zipf := rand.NewZipf(r, 1.1, 1, uint64(keys-1))
+
+	trace := make([]string, 0, n)
+	scanned := 0
+	for len(trace) < n {
+		if r.IntN(100) == 0 {
+			for range 200 {
+				trace = append(trace, "scan"+strconv.Itoa(scanned))
+				scanned++
+			}
+			continue
+		}
+		trace = append(trace, strconv.FormatUint(zipf.Uint64(), 10))
+	}
+
+	return trace
+}
@@ -0,0 +1,224 @@
+package lrucache
+
+import (
+	"context"
+	"hash/maphash"
+	"io"
+	"time"
+)
+
+// ShardedCache splits keys across independently locked InMemoryCache shards,
+// so readers of different keys do not contend for one lock.
+// LRU order and capacity are tracked per shard
+type ShardedCache[K comparable, V any] struct {
+	shards []*InMemoryCache[K, V]
+	seed   maphash.Seed
+}
+
+// NewShardedCache creates a cache of n shards
+//   - n: number of shards, limited by cap so that no shard is left without capacity
+//   - cap: max number of items across all shards, split evenly between them
+//   - ttl: default time to live for each cached item, NoExpiry to keep items until evicted
+//   - opts: optional behaviour applied to every shard
+func NewShardedCache[K comparable, V any](n, cap int, ttl time.Duration, opts ...Option) *ShardedCache[K, V] {
+	if cap > 0 && n > cap {
+		n = cap
+	}
+	n = max(n, 1)
+
+	c := &ShardedCache[K, V]{
+		shards: make([]*InMemoryCache[K, V], n),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range c.shards {
+		shardCap := 0
+		if cap > 0 {
+			shardCap = cap / n
+			if i < cap%n {
+				shardCap++
+			}
+		}
+		c.shards[i] = NewInMemoryCache[K, V](shardCap, ttl, opts...)
+	}
+
+	return c
+}
+
+func (c *ShardedCache[K, V]) shard(key K) *InMemoryCache[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Set inserts or updates a key-value pair in the shard of the key
+func (c *ShardedCache[K, V]) Set(key K, value V) error {
+	return c.shard(key).Set(key, value)
+}
+
+// SetWithTTL is Set with a TTL for this item only
+func (c *ShardedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
+	return c.shard(key).SetWithTTL(key, value, ttl)
+}
+
+// Get returns the value associated with the given key
+func (c *ShardedCache[K, V]) Get(key K) (V, error) {
+	return c.shard(key).Get(key)
+}
+
+// GetOrLoad returns the cached value or loads it, loads are deduplicated within the shard of the key
+func (c *ShardedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[V]) (V, LoadStatus, error) {
+	return c.shard(key).GetOrLoad(ctx, key, loader)
+}
+
+// Delete a key from the cache
+func (c *ShardedCache[K, V]) Delete(key K) error {
+	return c.shard(key).Delete(key)
+}
+
+// Peek returns the value and its expiration time without updating the LRU order
+func (c *ShardedCache[K, V]) Peek(key K) (V, time.Time, error) {
+	return c.shard(key).Peek(key)
+}
+
+// Purge removes all items from every shard
+func (c *ShardedCache[K, V]) Purge() {
+	for _, s := range c.shards {
+		s.Purge()
+	}
+}
+
+// Len returns the number of items across all shards
+func (c *ShardedCache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.Len()
+	}
+	return n
+}
+
+// Keys returns the keys of all shards, shard by shard
+func (c *ShardedCache[K, V]) Keys() []K {
+	keys := make([]K, 0, c.Len())
+	for _, s := range c.shards {
+		keys = append(keys, s.Keys()...)
+	}
+	return keys
+}
+
+// Range calls fn for every item, shard by shard, until fn returns false
+func (c *ShardedCache[K, V]) Range(fn func(key K, value V, expiredAt time.Time) bool) {
+	stopped := false
+	for _, s := range c.shards {
+		s.Range(func(key K, value V, expiredAt time.Time) bool {
+			stopped = !fn(key, value, expiredAt)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// Resize splits the capacity evenly between the shards, like NewShardedCache.
+// The number of shards does not change, so a capacity below it is rounded up to one item per shard
+func (c *ShardedCache[K, V]) Resize(cap int) int {
+	n := len(c.shards)
+	evicted := 0
+	for i, s := range c.shards {
+		shardCap := 0
+		if cap > 0 {
+			shardCap = cap / n
+			if i < cap%n {
+				shardCap++
+			}
+			// a zero capacity would remove the limit of the shard
+			shardCap = max(shardCap, 1)
+		}
+		evicted += s.Resize(shardCap)
+	}
+	return evicted
+}
+
+// TTLRemaining returns how long the item has left to live
+func (c *ShardedCache[K, V]) TTLRemaining(key K) (time.Duration, error) {
+	return c.shard(key).TTLRemaining(key)
+}
+
+// Stats returns the counters summed across all shards
+func (c *ShardedCache[K, V]) Stats() Stats {
+	var total Stats
+	for _, s := range c.shards {
+		st := s.Stats()
+		total.Len += st.Len
+		total.Capacity += st.Capacity
+		total.Cost += st.Cost
+		total.MaxCost += st.MaxCost
+		total.Hits += st.Hits
+		total.StaleHits += st.StaleHits
+		total.Misses += st.Misses
+		total.Evictions += st.Evictions
+		total.Expirations += st.Expirations
+	}
+	return total
+}
+
+// SetMaxCost splits the cost budget evenly between the shards, like Resize.
+// A budget below the number of shards is rounded up to a cost of one per shard
+func (c *ShardedCache[K, V]) SetMaxCost(maxCost int64, cost CostFunc[V]) {
+	n := int64(len(c.shards))
+	for i, s := range c.shards {
+		shardCost := maxCost
+		if maxCost > 0 {
+			shardCost = maxCost / n
+			if int64(i) < maxCost%n {
+				shardCost++
+			}
+			// a zero budget would remove the limit of the shard
+			shardCost = max(shardCost, 1)
+		}
+		s.SetMaxCost(shardCost, cost)
+	}
+}
+
+// OnEvict registers the hook on every shard
+func (c *ShardedCache[K, V]) OnEvict(hook EvictFunc[K, V]) {
+	for _, s := range c.shards {
+		s.OnEvict(hook)
+	}
+}
+
+// Snapshot writes the live items of all shards, the LRU order is kept within each shard
+func (c *ShardedCache[K, V]) Snapshot(w io.Writer) error {
+	now := time.Now()
+	var entries []snapshotEntry[K, V]
+	for _, s := range c.shards {
+		entries = append(entries, s.snapshotEntries(now)...)
+	}
+	return writeSnapshot(w, entries)
+}
+
+// Restore adds the items of a snapshot to the shards of their keys
+func (c *ShardedCache[K, V]) Restore(r io.Reader) (int, error) {
+	entries, createdAt, err := readSnapshot[K, V](r)
+	if err != nil {
+		return 0, err
+	}
+
+	elapsed := max(time.Since(createdAt), 0)
+	byShard := make(map[*InMemoryCache[K, V]][]snapshotEntry[K, V], len(c.shards))
+	for _, se := range entries {
+		s := c.shard(se.Key)
+		byShard[s] = append(byShard[s], se)
+	}
+
+	restored := 0
+	for s, entries := range byShard {
+		restored += s.restore(entries, elapsed)
+	}
+	return restored, nil
+}
+
+// StartCleanup launches the periodic cleanup of every shard
+func (c *ShardedCache[K, V]) StartCleanup(ctx context.Context, interval time.Duration) {
+	for _, s := range c.shards {
+		s.StartCleanup(ctx, interval)
+	}
+}
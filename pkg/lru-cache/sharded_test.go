@@ -0,0 +1,216 @@
+package lrucache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewShardedCache(t *testing.T) {
+	tests := []struct {
+		name           string
+		shards         int
+		capacity       int
+		wantShards     int
+		wantShardsCaps []int
+	}{
+		{
+			name:           "even split",
+			shards:         4,
+			capacity:       100,
+			wantShards:     4,
+			wantShardsCaps: []int{25, 25, 25, 25},
+		},
+		{
+			name:           "remainder goes to first shards",
+			shards:         3,
+			capacity:       10,
+			wantShards:     3,
+			wantShardsCaps: []int{4, 3, 3},
+		},
+		{
+			name:           "shards limited by capacity",
+			shards:         8,
+			capacity:       2,
+			wantShards:     2,
+			wantShardsCaps: []int{1, 1},
+		},
+		{
+			name:           "unlimited capacity",
+			shards:         2,
+			capacity:       0,
+			wantShards:     2,
+			wantShardsCaps: []int{0, 0},
+		},
+		{
+			name:           "zero shards",
+			shards:         0,
+			capacity:       10,
+			wantShards:     1,
+			wantShardsCaps: []int{10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewShardedCache[string, string](tt.shards, tt.capacity, time.Minute)
+
+			require.Len(t, cache.shards, tt.wantShards)
+			caps := make([]int, 0, len(cache.shards))
+			for _, s := range cache.shards {
+				caps = append(caps, s.cap)
+			}
+			require.Equal(t, tt.wantShardsCaps, caps)
+			require.Equal(t, tt.capacity, cache.Stats().Capacity)
+		})
+	}
+}
+
+func TestShardedCache_Operations(t *testing.T) {
+	cache := NewShardedCache[int, string](4, 0, time.Minute)
+
+	for i := range 100 {
+		require.NoError(t, cache.Set(i, "value"))
+	}
+	require.Equal(t, 100, cache.Len())
+
+	for i := range 100 {
+		got, err := cache.Get(i)
+		require.NoError(t, err)
+		require.Equal(t, "value", got)
+	}
+	_, err := cache.Get(1000)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, cache.Delete(0))
+	require.ErrorIs(t, cache.Delete(0), ErrNotFound)
+
+	stats := cache.Stats()
+	require.Equal(t, uint64(100), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+	require.Equal(t, 99, stats.Len)
+
+	cache.Purge()
+	require.Equal(t, 0, cache.Len())
+}
+
+func TestShardedCache_CapacityBound(t *testing.T) {
+	cache := NewShardedCache[int, int](4, 40, time.Minute)
+
+	for i := range 1000 {
+		require.NoError(t, cache.Set(i, i))
+	}
+
+	require.LessOrEqual(t, cache.Len(), 40)
+	for _, s := range cache.shards {
+		require.LessOrEqual(t, s.Len(), s.cap)
+	}
+}
+
+func TestShardedCache_StartCleanup(t *testing.T) {
+	cache := NewShardedCache[int, int](4, 0, 20*time.Millisecond)
+	for i := range 20 {
+		cache.Set(i, i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache.StartCleanup(ctx, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool { return cache.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestShardedCache_Concurrency(t *testing.T) {
+	cache := NewShardedCache[int, int](8, 100, time.Minute)
+
+	var wg sync.WaitGroup
+	for g := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				key := g*100 + j
+				cache.Set(key, j)
+				cache.Get(key)
+				if j%2 == 0 {
+					cache.Delete(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, cache.Len(), 100)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	single, err := NewFromConfig[string, int](Config{Capacity: 10, TTL: 1, Shards: 1})
+	require.NoError(t, err)
+	require.IsType(t, &InMemoryCache[string, int]{}, single)
+
+	sharded, err := NewFromConfig[string, int](Config{Capacity: 10, TTL: 1, Shards: 4, Policy: "tinylfu"})
+	require.NoError(t, err)
+	require.IsType(t, &ShardedCache[string, int]{}, sharded)
+
+	_, err = NewFromConfig[string, int](Config{Capacity: 10, TTL: 1, Policy: "fifo"})
+	require.ErrorIs(t, err, ErrUnknownPolicy)
+}
+
+func TestShardedCache_SetMaxCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxCost   int64
+		wantShard []int64
+		wantTotal int64
+	}{
+		{name: "remainder spread", maxCost: 10, wantShard: []int64{4, 3, 3}, wantTotal: 10},
+		{name: "budget below shard count", maxCost: 2, wantShard: []int64{1, 1, 1}, wantTotal: 3},
+		{name: "unbounded", maxCost: 0, wantShard: []int64{0, 0, 0}, wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewShardedCache[string, string](3, 0, time.Minute)
+			cache.SetMaxCost(tt.maxCost, func(v string) int64 { return int64(len(v)) })
+
+			for i, s := range cache.shards {
+				require.Equal(t, tt.wantShard[i], s.Stats().MaxCost)
+			}
+			require.Equal(t, tt.wantTotal, cache.Stats().MaxCost)
+		})
+	}
+}
+
+func TestShardedCache_Inspection(t *testing.T) {
+	cache := NewShardedCache[int, int](4, 100, time.Minute)
+	for i := range 40 {
+		cache.Set(i, i)
+	}
+
+	require.ElementsMatch(t, cache.Keys(), func() []int {
+		keys := make([]int, 40)
+		for i := range keys {
+			keys[i] = i
+		}
+		return keys
+	}())
+
+	visited := 0
+	cache.Range(func(int, int, time.Time) bool {
+		visited++
+		return visited < 10
+	})
+	require.Equal(t, 10, visited, "Range() should stop across shards")
+
+	remaining, err := cache.TTLRemaining(1)
+	require.NoError(t, err)
+	require.InDelta(t, time.Minute, remaining, float64(time.Second))
+
+	evicted := cache.Resize(20)
+	require.Equal(t, 40-evicted, cache.Len())
+	require.LessOrEqual(t, cache.Len(), 20)
+	require.Equal(t, 20, cache.Stats().Capacity)
+}
@@ -0,0 +1,20 @@
+package lrucache
+
+import "time"
+
+// AnyCache is the untyped cache returned by New.
+//
+// Breaking change: InMemoryCache now takes type parameters, so code that names the type,
+// like a *lrucache.InMemoryCache field or argument, no longer compiles and has to switch
+// to *AnyCache or to concrete types. Calls of New and of the cache methods are unaffected
+//
+// Deprecated: use InMemoryCache with concrete key and value types
+type AnyCache = InMemoryCache[any, any]
+
+// New creates an untyped cache with the interface{} API of the previous, non-generic InMemoryCache
+//
+// Deprecated: use NewInMemoryCache, it avoids boxing keys and values
+// and type assertions on every Get
+func New(cap int, ttl time.Duration) *AnyCache {
+	return NewInMemoryCache[any, any](cap, ttl)
+}
@@ -0,0 +1,38 @@
+package lrucache
+
+// SimulationResult is the outcome of replaying an access trace
+type SimulationResult struct {
+	Policy   Policy
+	Capacity int
+	Requests int
+	Hits     int
+}
+
+// HitRatio returns the share of requests served from the cache
+func (r SimulationResult) HitRatio() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Requests)
+}
+
+// Simulate replays an access trace against an empty cache of the given policy and capacity.
+// Every key is read and set on a miss, the way a read-through cache is filled
+func Simulate[K comparable](p Policy, capacity int, trace []K) SimulationResult {
+	cache := NewInMemoryCache[K, struct{}](capacity, NoExpiry, WithPolicy(p))
+
+	for _, key := range trace {
+		if _, err := cache.Get(key); err != nil {
+			_ = cache.Set(key, struct{}{})
+		}
+	}
+
+	stats := cache.Stats()
+
+	return SimulationResult{
+		Policy:   p,
+		Capacity: capacity,
+		Requests: len(trace),
+		Hits:     int(stats.Hits),
+	}
+}
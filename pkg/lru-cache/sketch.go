@@ -0,0 +1,85 @@
+package lrucache
+
+import "math/bits"
+
+const (
+	sketchDepth = 4
+	// sketchMaxCount saturates the counters, TinyLFU only needs to tell rare items from frequent ones
+	sketchMaxCount = 15
+	// sketchSampleFactor times the width is the number of additions after which all counters are halved
+	sketchSampleFactor = 10
+)
+
+// sketchSeeds give each row its own hash of the key
+var sketchSeeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// countMinSketch estimates how often a key hash was seen, it may overestimate
+// on collisions but never underestimates. Counters are periodically halved,
+// so the estimates follow recent popularity instead of the all-time one
+type countMinSketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// newCountMinSketch creates a sketch with at least width counters per row
+func newCountMinSketch(width int) *countMinSketch {
+	width = 1 << bits.Len(uint(max(width, 16)-1))
+
+	s := &countMinSketch{
+		mask:    uint64(width - 1),
+		resetAt: width * sketchSampleFactor,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+
+	return s
+}
+
+func (s *countMinSketch) index(hash uint64, row int) uint64 {
+	h := (hash + sketchSeeds[row]) * 0x9e3779b97f4a7c15
+	h ^= h >> 32
+	return h & s.mask
+}
+
+func (s *countMinSketch) add(hash uint64) {
+	for i := range s.rows {
+		idx := s.index(hash, i)
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.halve()
+	}
+}
+
+func (s *countMinSketch) estimate(hash uint64) uint8 {
+	est := uint8(sketchMaxCount)
+	for i := range s.rows {
+		est = min(est, s.rows[i][s.index(hash, i)])
+	}
+	return est
+}
+
+func (s *countMinSketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	s.additions = 0
+}
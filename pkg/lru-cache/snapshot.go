@@ -0,0 +1,210 @@
+package lrucache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	snapshotMagic = "LRUSNAP\x00"
+	// snapshotVersion must be bumped whenever the layout of the snapshot changes
+	snapshotVersion uint16 = 1
+)
+
+// snapshotHeader precedes the gob encoded entries, all fields are big endian
+type snapshotHeader struct {
+	Magic     [8]byte
+	Version   uint16
+	CreatedAt int64 // unix nanoseconds
+	Size      uint64
+	Checksum  uint32 // CRC-32 (IEEE) of the entries
+}
+
+// snapshotEntry is a live item, Remaining is NoExpiry for items that never expire
+type snapshotEntry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	TTL       time.Duration
+	Remaining time.Duration
+}
+
+// Snapshot writes the live items with their remaining TTL to w, from the least to the most
+// recently used one, so Restore rebuilds the LRU order. Keys and values are gob encoded,
+// interface types stored in them must be registered with gob.Register
+func (c *InMemoryCache[K, V]) Snapshot(w io.Writer) error {
+	return writeSnapshot(w, c.snapshotEntries(time.Now()))
+}
+
+// Restore adds the items of a snapshot written by Snapshot, skipping the ones expired since.
+// A snapshot of another version or with a bad checksum is rejected with ErrInvalidSnapshot
+// before any item is added. It returns the number of restored items
+func (c *InMemoryCache[K, V]) Restore(r io.Reader) (int, error) {
+	entries, createdAt, err := readSnapshot[K, V](r)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.restore(entries, max(time.Since(createdAt), 0)), nil
+}
+
+// snapshotEntries returns the live items, the least valuable first
+func (c *InMemoryCache[K, V]) snapshotEntries(now time.Time) []snapshotEntry[K, V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make([]snapshotEntry[K, V], 0, len(c.items))
+	c.policy.each(func(e *entry[K, V]) {
+		if e.expired(now) {
+			return
+		}
+		remaining := NoExpiry
+		if !e.expiredAt.IsZero() {
+			remaining = e.expiredAt.Sub(now)
+		}
+		entries = append(entries, snapshotEntry[K, V]{Key: e.key, Value: e.value, TTL: e.ttl, Remaining: remaining})
+	})
+	slices.Reverse(entries)
+
+	return entries
+}
+
+func (c *InMemoryCache[K, V]) restore(entries []snapshotEntry[K, V], elapsed time.Duration) int {
+	restored := 0
+	for _, se := range entries {
+		if se.Remaining != NoExpiry && se.Remaining <= elapsed {
+			continue
+		}
+
+		c.mu.Lock()
+		evicted, err := c.set(se.Key, se.Value, se.TTL)
+		if e, ok := c.items[se.Key]; ok && err == nil && se.Remaining != NoExpiry {
+			e.expiredAt = time.Now().Add(se.Remaining - elapsed)
+			c.schedule(e)
+		}
+		hooks := c.hooks
+		c.mu.Unlock()
+
+		notify(hooks, evicted...)
+		if err == nil {
+			restored++
+		}
+	}
+
+	return restored
+}
+
+func writeSnapshot[K comparable, V any](w io.Writer, entries []snapshotEntry[K, V]) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(entries); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	header := snapshotHeader{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UnixNano(),
+		Size:      uint64(payload.Len()),
+		Checksum:  crc32.ChecksumIEEE(payload.Bytes()),
+	}
+	copy(header.Magic[:], snapshotMagic)
+
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+	if _, err := payload.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func readSnapshot[K comparable, V any](r io.Reader) ([]snapshotEntry[K, V], time.Time, error) {
+	var header snapshotHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: failed to read header: %w", ErrInvalidSnapshot, err)
+	}
+	if string(header.Magic[:]) != snapshotMagic {
+		return nil, time.Time{}, fmt.Errorf("%w: not a cache snapshot", ErrInvalidSnapshot)
+	}
+	if header.Version != snapshotVersion {
+		return nil, time.Time{}, fmt.Errorf("%w: version %d, want %d", ErrInvalidSnapshot, header.Version, snapshotVersion)
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r, int64(header.Size)))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: failed to read entries: %w", ErrInvalidSnapshot, err)
+	}
+	if uint64(len(payload)) != header.Size || crc32.ChecksumIEEE(payload) != header.Checksum {
+		return nil, time.Time{}, fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+
+	var entries []snapshotEntry[K, V]
+	if err = gob.NewDecoder(bytes.NewReader(payload)).Decode(&entries); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: failed to decode entries: %w", ErrInvalidSnapshot, err)
+	}
+
+	return entries, time.Unix(0, header.CreatedAt), nil
+}
+
+// Snapshotter is a cache that can be saved to and restored from a snapshot
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+	Restore(r io.Reader) (int, error)
+}
+
+// SaveSnapshot writes a snapshot of the cache to path, replacing the file atomically
+// so a crash while writing leaves the previous snapshot intact
+func SaveSnapshot(path string, c Snapshotter) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	if err = c.Snapshot(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync snapshot file: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot restores the cache from the snapshot at path and removes the file,
+// so a snapshot is never restored twice. A missing file restores nothing,
+// an invalid one is removed too and ErrInvalidSnapshot is returned
+func LoadSnapshot(path string, c Snapshotter) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(path)
+	}()
+
+	return c.Restore(f)
+}
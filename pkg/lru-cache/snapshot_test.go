@@ -0,0 +1,150 @@
+package lrucache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type snapshotOrder struct {
+	ID    string
+	Items []int
+}
+
+func TestInMemoryCache_SnapshotRestore(t *testing.T) {
+	src := NewInMemoryCache[string, *snapshotOrder](3, time.Minute)
+	require.NoError(t, src.Set("key1", &snapshotOrder{ID: "1", Items: []int{1}}))
+	require.NoError(t, src.SetWithTTL("key2", &snapshotOrder{ID: "2"}, NoExpiry))
+	require.NoError(t, src.Set("key3", &snapshotOrder{ID: "3"}))
+	_, err := src.Get("key1")
+	require.NoError(t, err)
+	_, wantExpiredAt, err := src.Peek("key1")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf))
+
+	dst := NewInMemoryCache[string, *snapshotOrder](3, time.Minute)
+	restored, err := dst.Restore(&buf)
+	require.NoError(t, err)
+	require.Equal(t, 3, restored)
+
+	got, expiredAt, err := dst.Peek("key1")
+	require.NoError(t, err)
+	require.Equal(t, &snapshotOrder{ID: "1", Items: []int{1}}, got)
+	require.WithinDuration(t, wantExpiredAt, expiredAt, 10*time.Millisecond, "remaining TTL should be kept")
+
+	_, expiredAt, err = dst.Peek("key2")
+	require.NoError(t, err)
+	require.True(t, expiredAt.IsZero(), "item without expiry should be restored without expiry")
+
+	// key2 is the least recently used item, as it was before the snapshot
+	require.NoError(t, dst.Set("key4", &snapshotOrder{ID: "4"}))
+	_, _, err = dst.Peek("key2")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestInMemoryCache_RestoreSkipsExpired(t *testing.T) {
+	src := NewInMemoryCache[string, string](10, time.Minute)
+	require.NoError(t, src.SetWithTTL("short", "value", 20*time.Millisecond))
+	require.NoError(t, src.Set("long", "value"))
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf))
+	time.Sleep(30 * time.Millisecond)
+
+	dst := NewInMemoryCache[string, string](10, time.Minute)
+	restored, err := dst.Restore(&buf)
+	require.NoError(t, err)
+	require.Equal(t, 1, restored)
+	_, _, err = dst.Peek("short")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestInMemoryCache_RestoreInvalid(t *testing.T) {
+	src := NewInMemoryCache[string, string](10, time.Minute)
+	require.NoError(t, src.Set("key", "value"))
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf))
+	valid := buf.Bytes()
+
+	headerSize := binary.Size(snapshotHeader{})
+
+	tests := []struct {
+		name    string
+		corrupt func(b []byte) []byte
+	}{
+		{
+			name:    "empty",
+			corrupt: func([]byte) []byte { return nil },
+		},
+		{
+			name: "bad magic",
+			corrupt: func(b []byte) []byte {
+				b[0] = 'X'
+				return b
+			},
+		},
+		{
+			name: "other version",
+			corrupt: func(b []byte) []byte {
+				binary.BigEndian.PutUint16(b[8:], snapshotVersion+1)
+				return b
+			},
+		},
+		{
+			name: "flipped payload byte",
+			corrupt: func(b []byte) []byte {
+				b[headerSize+1] ^= 0xff
+				return b
+			},
+		},
+		{
+			name:    "truncated",
+			corrupt: func(b []byte) []byte { return b[:len(b)-1] },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.corrupt(bytes.Clone(valid))
+
+			dst := NewInMemoryCache[string, string](10, time.Minute)
+			restored, err := dst.Restore(bytes.NewReader(data))
+			require.ErrorIs(t, err, ErrInvalidSnapshot)
+			require.Zero(t, restored)
+			require.Zero(t, dst.Len())
+		})
+	}
+}
+
+func TestSaveLoadSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	restored, err := LoadSnapshot(path, NewInMemoryCache[string, string](10, time.Minute))
+	require.NoError(t, err, "missing snapshot should be ignored")
+	require.Zero(t, restored)
+
+	src := NewShardedCache[string, string](4, 100, time.Minute)
+	for _, key := range []string{"key1", "key2", "key3"} {
+		require.NoError(t, src.Set(key, "value"))
+	}
+	require.NoError(t, SaveSnapshot(path, src))
+
+	dst := NewShardedCache[string, string](2, 100, time.Minute)
+	restored, err = LoadSnapshot(path, dst)
+	require.NoError(t, err)
+	require.Equal(t, 3, restored)
+	require.Equal(t, 3, dst.Len())
+
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist, "snapshot should be removed once restored")
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Empty(t, entries, "no temporary files should be left")
+}
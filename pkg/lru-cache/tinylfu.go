@@ -0,0 +1,185 @@
+package lrucache
+
+import (
+	"hash/maphash"
+	"math"
+)
+
+const (
+	// tinyLFUWindowPercent of the capacity is the LRU window that every new item enters
+	tinyLFUWindowPercent = 1
+	// tinyLFUProtectedPercent of the main segment holds the items read again after admission
+	tinyLFUProtectedPercent = 80
+	// tinyLFUSketchWidth is used when the cache is bounded by cost only and its capacity is unknown
+	tinyLFUSketchWidth = 1 << 16
+)
+
+// entry segments of the W-TinyLFU policy
+const (
+	segmentWindow uint8 = iota
+	segmentProbation
+	segmentProtected
+)
+
+// tinyLFUPolicy implements W-TinyLFU. New items enter the window, and when the window overflows
+// its oldest item, the candidate, competes with the eviction victim of the main segmented LRU:
+// the one the sketch estimates to be less frequent is evicted.
+// Items read again while on probation are promoted to the protected part of the main segment
+type tinyLFUPolicy[K comparable, V any] struct {
+	window    *lruList[K, V]
+	probation *lruList[K, V]
+	protected *lruList[K, V]
+
+	sketch *countMinSketch
+	seed   maphash.Seed
+	// capacity is zero if the cache is bounded by cost only,
+	// then the window is sized by the number of items instead
+	capacity int
+}
+
+func newTinyLFUPolicy[K comparable, V any](capacity int) *tinyLFUPolicy[K, V] {
+	width := capacity
+	if width <= 0 {
+		width = tinyLFUSketchWidth
+	}
+
+	return &tinyLFUPolicy[K, V]{
+		window:    newLRUList[K, V](),
+		probation: newLRUList[K, V](),
+		protected: newLRUList[K, V](),
+		sketch:    newCountMinSketch(width),
+		seed:      maphash.MakeSeed(),
+		capacity:  capacity,
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) add(e *entry[K, V]) {
+	p.sketch.add(p.hash(e.key))
+	e.segment = segmentWindow
+	p.window.PushFront(e)
+
+	// while the main segment has room, the window overflow is admitted without competing
+	for p.window.Len() > p.windowCap() && p.mainLen() < p.mainCap() {
+		p.moveTo(p.window.Back(), p.probation, segmentProbation)
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) access(e *entry[K, V]) {
+	p.sketch.add(p.hash(e.key))
+
+	switch e.segment {
+	case segmentWindow:
+		p.window.MoveToFront(e)
+	case segmentProbation:
+		p.moveTo(e, p.protected, segmentProtected)
+		if p.protected.Len() > p.protectedCap() {
+			p.moveTo(p.protected.Back(), p.probation, segmentProbation)
+		}
+	case segmentProtected:
+		p.protected.MoveToFront(e)
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) miss(key K) {
+	p.sketch.add(p.hash(key))
+}
+
+func (p *tinyLFUPolicy[K, V]) remove(e *entry[K, V]) {
+	p.list(e.segment).Remove(e)
+}
+
+// selectVictim admits the window candidate to probation when it beats the main victim,
+// the new item is never the candidate since it is at the front of the window
+func (p *tinyLFUPolicy[K, V]) selectVictim(*entry[K, V]) *entry[K, V] {
+	mainVictim := p.probation.Back()
+	if mainVictim == nil {
+		mainVictim = p.protected.Back()
+	}
+
+	var candidate *entry[K, V]
+	if p.window.Len() > p.windowCap() {
+		candidate = p.window.Back()
+	}
+
+	switch {
+	case candidate == nil && mainVictim == nil:
+		return p.window.Back()
+	case candidate == nil:
+		return mainVictim
+	case mainVictim == nil:
+		return candidate
+	}
+
+	if p.sketch.estimate(p.hash(candidate.key)) > p.sketch.estimate(p.hash(mainVictim.key)) {
+		p.moveTo(candidate, p.probation, segmentProbation)
+		return mainVictim
+	}
+
+	return candidate
+}
+
+func (p *tinyLFUPolicy[K, V]) each(fn func(e *entry[K, V])) {
+	p.protected.each(fn)
+	p.probation.each(fn)
+	p.window.each(fn)
+}
+
+func (p *tinyLFUPolicy[K, V]) reset() {
+	p.window.Init()
+	p.probation.Init()
+	p.protected.Init()
+	p.sketch.reset()
+}
+
+// resize keeps the sketch, so the frequencies seen so far still guide admission.
+// Segments over their new size shrink as items are evicted and promoted
+func (p *tinyLFUPolicy[K, V]) resize(capacity int) {
+	p.capacity = capacity
+}
+
+func (p *tinyLFUPolicy[K, V]) hash(key K) uint64 {
+	return maphash.Comparable(p.seed, key)
+}
+
+func (p *tinyLFUPolicy[K, V]) list(segment uint8) *lruList[K, V] {
+	switch segment {
+	case segmentProbation:
+		return p.probation
+	case segmentProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) moveTo(e *entry[K, V], to *lruList[K, V], segment uint8) {
+	p.list(e.segment).Remove(e)
+	e.segment = segment
+	to.PushFront(e)
+}
+
+func (p *tinyLFUPolicy[K, V]) mainLen() int {
+	return p.probation.Len() + p.protected.Len()
+}
+
+func (p *tinyLFUPolicy[K, V]) windowCap() int {
+	capacity := p.capacity
+	if capacity <= 0 {
+		capacity = p.window.Len() + p.mainLen()
+	}
+	return max(1, capacity*tinyLFUWindowPercent/100)
+}
+
+func (p *tinyLFUPolicy[K, V]) mainCap() int {
+	if p.capacity <= 0 {
+		return math.MaxInt
+	}
+	return p.capacity - p.windowCap()
+}
+
+func (p *tinyLFUPolicy[K, V]) protectedCap() int {
+	if p.capacity <= 0 {
+		return math.MaxInt
+	}
+	return p.mainCap() * tinyLFUProtectedPercent / 100
+}
@@ -12,13 +12,13 @@ import (
 )
 
 type Config struct {
-	Host     string `env:"HOST"      env-default:"localhost" yaml:"host"`
-	Port     uint16 `env:"PORT"      env-default:"5432"      yaml:"port"`
-	Username string `env:"USER"      env-default:"postgres"  yaml:"user"`
-	Password string `env:"PASSWORD"  env-default:"1234"      yaml:"password"`
-	Database string `env:"DB"        env-default:"postgres"  yaml:"db"`
-	MaxConns int32  `env:"MAX_CONNS" env-default:"10"        yaml:"max_conns"`
-	MinConns int32  `env:"MIN_CONNS" env-default:"5"         yaml:"min_conns"`
+	Host     string `env:"HOST"      env-default:"localhost" json:"host"      yaml:"host"`
+	Port     uint16 `env:"PORT"      env-default:"5432"      json:"port"      yaml:"port"`
+	Username string `env:"USER"      env-default:"postgres"  json:"user"      yaml:"user"`
+	Password string `env:"PASSWORD"  env-default:"1234"      json:"password"  secret:"true" yaml:"password"`
+	Database string `env:"DB"        env-default:"postgres"  json:"db"        yaml:"db"`
+	MaxConns int32  `env:"MAX_CONNS" env-default:"10"        json:"max_conns" yaml:"max_conns"`
+	MinConns int32  `env:"MIN_CONNS" env-default:"5"         json:"min_conns" yaml:"min_conns"`
 }
 
 func New(ctx context.Context, config Config) (*pgxpool.Pool, error) {
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type Config struct {
+	Host           string `env:"HOST"             env-default:"localhost" json:"host"             yaml:"host"`
+	Port           uint16 `env:"PORT"             env-default:"6379"      json:"port"             yaml:"port"`
+	Password       string `env:"PASSWORD"         json:"password"         secret:"true"           yaml:"password"`
+	DB             int    `env:"DB"               env-default:"0"         json:"db"               yaml:"db"`
+	PoolSize       int    `env:"POOL_SIZE"        env-default:"10"        json:"pool_size"        yaml:"pool_size"`
+	MinIdleConns   int    `env:"MIN_IDLE_CONNS"   env-default:"2"         json:"min_idle_conns"   yaml:"min_idle_conns"`
+	DialTimeoutMs  int    `env:"DIAL_TIMEOUT_MS"  env-default:"5000"      json:"dial_timeout_ms"  yaml:"dial_timeout_ms"`
+	ReadTimeoutMs  int    `env:"READ_TIMEOUT_MS"  env-default:"1000"      json:"read_timeout_ms"  yaml:"read_timeout_ms"`
+	WriteTimeoutMs int    `env:"WRITE_TIMEOUT_MS" env-default:"1000"      json:"write_timeout_ms" yaml:"write_timeout_ms"`
+}
+
+// New creates a pooled client and checks that the server is reachable
+func New(ctx context.Context, config Config) (*goredis.Client, error) {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:         net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port))),
+		Password:     config.Password,
+		DB:           config.DB,
+		PoolSize:     config.PoolSize,
+		MinIdleConns: config.MinIdleConns,
+		DialTimeout:  time.Duration(config.DialTimeoutMs) * time.Millisecond,
+		ReadTimeout:  time.Duration(config.ReadTimeoutMs) * time.Millisecond,
+		WriteTimeout: time.Duration(config.WriteTimeoutMs) * time.Millisecond,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %v", err)
+	}
+
+	return client, nil
+}